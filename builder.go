@@ -0,0 +1,142 @@
+package senml
+
+import (
+	"fmt"
+	"time"
+)
+
+// BuilderError is an error which is returned when a method of a Builder which modifies the last record was called before a record was added.
+type BuilderError struct {
+	// The name of the method
+	Method string
+}
+
+func (err *BuilderError) Error() string {
+	return fmt.Sprintf("The method %v of the builder was called before a record was added", err.Method)
+}
+
+func newBuilderError(method string) *BuilderError {
+	return &BuilderError{
+		Method: method,
+	}
+}
+
+// Builder constructs a message record by record without taking the addresses of values. The base fields are placed on the first record.
+// Errors are collected and returned by Build, so calls can be chained.
+type Builder struct {
+	baseName *string
+	baseTime *float64
+	baseUnit *string
+	records  []Record
+	err      error
+}
+
+// NewPack returns a builder for a message with the given base name. The base name may be empty.
+func NewPack(baseName string) *Builder {
+	var builder = &Builder{}
+	if baseName != "" {
+		builder.baseName = &baseName
+	}
+	return builder
+}
+
+// BaseTime sets the base time of the message, which the times set by At are relative to.
+func (builder *Builder) BaseTime(baseTime time.Time) *Builder {
+	var seconds = float64(baseTime.Unix()) + float64(baseTime.Nanosecond())/float64(time.Second)
+	builder.baseTime = &seconds
+	return builder
+}
+
+// BaseUnit sets the base unit of the message, which is used by all records without a unit.
+func (builder *Builder) BaseUnit(baseUnit string) *Builder {
+	builder.baseUnit = &baseUnit
+	return builder
+}
+
+// Add adds a record with the name and the floating-point value.
+func (builder *Builder) Add(name string, value float64) *Builder {
+	return builder.add(Record{Name: &name, Value: &value})
+}
+
+// AddBool adds a record with the name and the boolean value.
+func (builder *Builder) AddBool(name string, value bool) *Builder {
+	return builder.add(Record{Name: &name, BoolValue: &value})
+}
+
+// AddString adds a record with the name and the string value.
+func (builder *Builder) AddString(name string, value string) *Builder {
+	return builder.add(Record{Name: &name, StringValue: &value})
+}
+
+// AddData adds a record with the name and the binary data, which is encoded as base64url without padding.
+func (builder *Builder) AddData(name string, value []byte) *Builder {
+	var record = Record{Name: &name}
+	record.SetDataValueBytes(value)
+	return builder.add(record)
+}
+
+// AddSum adds a record with the name and the sum.
+func (builder *Builder) AddSum(name string, sum float64) *Builder {
+	return builder.add(Record{Name: &name, Sum: &sum})
+}
+
+// At sets the time of the last record relative to the base time. Without a base time, the time is relative to the time of resolving the message.
+func (builder *Builder) At(offset time.Duration) *Builder {
+	if record := builder.lastRecord("At"); record != nil {
+		var seconds = offset.Seconds()
+		record.Time = &seconds
+	}
+	return builder
+}
+
+// Unit sets the unit of the last record.
+func (builder *Builder) Unit(unit string) *Builder {
+	if record := builder.lastRecord("Unit"); record != nil {
+		record.Unit = &unit
+	}
+	return builder
+}
+
+// Build returns the message. The base fields are placed on the first record.
+// Returns a RecordError containing an InvalidNameError if the name of a record resolves to an invalid name (RFC 8428 chapter 4.5.1),
+// or a BuilderError if At or Unit were called before a record was added.
+func (builder *Builder) Build() (Message, error) {
+	if builder.err != nil {
+		return Message{}, builder.err
+	}
+	var message Message
+	message.Records = make([]Record, len(builder.records))
+	copy(message.Records, builder.records)
+	for index, record := range message.Records {
+		if _, err := resolveName(builder.baseName, record.Name, StrictNameValidation); err != nil {
+			return Message{}, &RecordError{
+				Index: index,
+				Field: "n",
+				Err:   err,
+			}
+		}
+	}
+	if len(message.Records) > 0 {
+		var first = &message.Records[0]
+		first.BaseName = builder.baseName
+		first.BaseTime = builder.baseTime
+		first.BaseUnit = builder.baseUnit
+	}
+	return message, nil
+}
+
+func (builder *Builder) add(record Record) *Builder {
+	builder.records = append(builder.records, record)
+	return builder
+}
+
+// lastRecord returns the last added record. If no record was added yet, a BuilderError is kept for Build and nil is returned.
+func (builder *Builder) lastRecord(method string) *Record {
+	if len(builder.records) == 0 {
+		if builder.err == nil {
+			builder.err = newBuilderError(method)
+		}
+		return nil
+	}
+	return &builder.records[len(builder.records)-1]
+}
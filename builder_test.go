@@ -0,0 +1,86 @@
+package senml_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	senml "github.com/nkristek/go-senml"
+)
+
+func TestBuilder(t *testing.T) {
+	message, err := senml.NewPack("urn:dev:ow:10e2073a01080063:").
+		BaseTime(time.Unix(1320067464, 500000000)).
+		BaseUnit("Cel").
+		Add("temp", 21.3).
+		Add("temp", 21.5).At(60*time.Second).
+		AddBool("door", true).
+		AddString("label", "Machine Room").
+		AddData("raw", []byte("hi")).
+		AddSum("energy", 1.5).Unit("kWh").
+		Build()
+	if err != nil {
+		t.Error("Building the message failed: ", err)
+		return
+	}
+
+	encodedMessage, err := message.Encode(senml.JSON)
+	if err != nil {
+		t.Error("Encoding JSON failed: ", err)
+		return
+	}
+	const expected = `[{"bn":"urn:dev:ow:10e2073a01080063:","bt":1320067464.5,"bu":"Cel","n":"temp","v":21.3},{"n":"temp","v":21.5,"t":60},{"n":"door","vb":true},{"n":"label","vs":"Machine Room"},{"n":"raw","vd":"aGk"},{"n":"energy","u":"kWh","s":1.5}]`
+	if string(encodedMessage) != expected {
+		t.Errorf("Building the message resulted in an unexpected message. expected: %s, got: %s", expected, encodedMessage)
+	}
+
+	if err := message.Validate(); err != nil {
+		t.Error("The built message should be valid: ", err)
+	}
+}
+
+func TestBuilderWithoutRecords(t *testing.T) {
+	message, err := senml.NewPack("urn:dev:ow:10e2073a01080063:").Build()
+	if err != nil {
+		t.Error("Building an empty message failed: ", err)
+		return
+	}
+	if len(message.Records) != 0 {
+		t.Error("The message should not contain records")
+	}
+}
+
+func TestBuilderInvalid(t *testing.T) {
+	_, err := senml.NewPack("urn:dev:ow:10e2073a01080063:").Add("temp", 21.3).Add("temp?", 21.5).Build()
+	recordError, ok := err.(*senml.RecordError)
+	if !ok {
+		t.Error("Building a message with an invalid name should result in a RecordError, got: ", err)
+		return
+	}
+	if recordError.Index != 1 || recordError.Field != "n" {
+		t.Errorf("The error contains an unexpected record or field: %v", recordError)
+	}
+	if invalidNameError, ok := recordError.Err.(*senml.InvalidNameError); !ok || invalidNameError.Reason != senml.ContainsInvalidCharacter {
+		t.Error("The RecordError should contain an InvalidNameError, got: ", recordError.Err)
+	}
+
+	_, err = senml.NewPack("").Add("", 21.3).Build()
+	if recordError, ok := err.(*senml.RecordError); !ok || recordError.Err.(*senml.InvalidNameError).Reason != senml.Empty {
+		t.Error("Building a message with an empty name should result in an InvalidNameError, got: ", err)
+	}
+
+	_, err = senml.NewPack("").At(time.Second).Add("temp", 21.3).Build()
+	if builderError, ok := err.(*senml.BuilderError); !ok || builderError.Method != "At" {
+		t.Error("Calling At before adding a record should result in a BuilderError, got: ", err)
+	}
+}
+
+func TestBuilderError(t *testing.T) {
+	err := &senml.BuilderError{
+		Method: "Unit",
+	}
+	message := err.Error()
+	if !strings.Contains(message, "Unit") {
+		t.Error("The error message does not contain the method.")
+	}
+}
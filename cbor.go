@@ -0,0 +1,711 @@
+package senml
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// CBOR labels of the SenML fields as defined in RFC 8428 chapter 6
+const (
+	cborLabelBaseVersion = -1
+	cborLabelBaseName    = -2
+	cborLabelBaseTime    = -3
+	cborLabelBaseUnit    = -4
+	cborLabelBaseValue   = -5
+	cborLabelBaseSum     = -6
+	cborLabelName        = 0
+	cborLabelUnit        = 1
+	cborLabelValue       = 2
+	cborLabelStringValue = 3
+	cborLabelBoolValue   = 4
+	cborLabelSum         = 5
+	cborLabelTime        = 6
+	cborLabelUpdateTime  = 7
+	cborLabelDataValue   = 8
+
+	// CBOR labels of the content type fields as defined in RFC 9193 chapter 6
+	cborLabelBaseContentType = -24
+	cborLabelContentType     = 27
+)
+
+// CBOR major types as defined in RFC 7049 chapter 2.1
+const (
+	cborMajorUnsignedInt byte = 0
+	cborMajorNegativeInt byte = 1
+	cborMajorByteString  byte = 2
+	cborMajorTextString  byte = 3
+	cborMajorArray       byte = 4
+	cborMajorMap         byte = 5
+	cborMajorTag         byte = 6
+	cborMajorSimple      byte = 7
+)
+
+// the additional information value which marks an indefinite length item or the "break" stop code
+const cborIndefinite byte = 31
+
+// the maximum nesting depth of arrays, maps and tags which is accepted while decoding
+const cborMaxDepth = 32
+
+// InvalidCBORError is an error which is returned when a CBOR encoded message is malformed or does not have the structure of a SenML pack.
+type InvalidCBORError struct {
+	// The byte offset in the encoded message at which the error was detected
+	Offset int
+
+	// The reason why the message is invalid
+	Reason string
+}
+
+func (err *InvalidCBORError) Error() string {
+	return fmt.Sprintf("Invalid CBOR at offset %v: %v", err.Offset, err.Reason)
+}
+
+func newInvalidCBORError(offset int, reason string) *InvalidCBORError {
+	return &InvalidCBORError{
+		Offset: offset,
+		Reason: reason,
+	}
+}
+
+// cborMapEntry is a single key/value pair of a decoded CBOR map. The order of the entries is kept.
+type cborMapEntry struct {
+	key   interface{}
+	value interface{}
+
+	// The byte offset of the value in the encoded data
+	offset int
+}
+
+func encodeCBOR(message Message) ([]byte, error) {
+	var encoder = cborEncoder{}
+	encoder.writeHead(cborMajorArray, uint64(len(message.Records)))
+	for _, record := range message.Records {
+		if err := encoder.writeRecord(record); err != nil {
+			return nil, err
+		}
+	}
+	return encoder.buffer.Bytes(), nil
+}
+
+func decodeCBOR(encodedMessage []byte) (message Message, err error) {
+	var decoder = cborDecoder{data: encodedMessage}
+	major, info, argument, err := decoder.readHead()
+	if err != nil {
+		return
+	}
+	if major != cborMajorArray {
+		err = newInvalidCBORError(0, "the pack is not an array")
+		return
+	}
+	for i := uint64(0); info == cborIndefinite || i < argument; i++ {
+		var start = decoder.offset
+		var item interface{}
+		item, err = decoder.readItem(1)
+		if err != nil {
+			return
+		}
+		if _, isBreak := item.(cborBreak); isBreak && info == cborIndefinite {
+			break
+		}
+		entries, ok := item.([]cborMapEntry)
+		if !ok {
+			err = newInvalidCBORError(start, "the record is not a map")
+			return
+		}
+		var record Record
+		record, err = decodeCBORRecord(entries)
+		if err != nil {
+			return
+		}
+		message.Records = append(message.Records, record)
+	}
+	if decoder.offset != len(decoder.data) {
+		err = newInvalidCBORError(decoder.offset, "unexpected data after the end of the pack")
+	}
+	return
+}
+
+func decodeCBORRecord(entries []cborMapEntry) (record Record, err error) {
+	for _, entry := range entries {
+		label, ok := entry.key.(int64)
+		if !ok {
+			if textLabel, ok := entry.key.(string); ok {
+				record.Extensions = addCBORExtension(record.Extensions, textLabel, entry.value)
+			}
+			continue
+		}
+		if entry.value == nil && recordCBORLabels[int(label)] {
+			// null fields are treated like missing fields, like encoding/json does. iPATCH documents use them to remove records (RFC 8790 chapter 4.3).
+			continue
+		}
+		switch label {
+		case cborLabelBaseVersion:
+			record.BaseVersion, err = cborIntField(label, entry)
+		case cborLabelBaseName:
+			record.BaseName, err = cborStringField(label, entry)
+		case cborLabelBaseTime:
+			record.BaseTime, err = cborFloatField(label, entry)
+		case cborLabelBaseUnit:
+			record.BaseUnit, err = cborStringField(label, entry)
+		case cborLabelBaseValue:
+			record.BaseValue, err = cborFloatField(label, entry)
+		case cborLabelBaseSum:
+			record.BaseSum, err = cborFloatField(label, entry)
+		case cborLabelName:
+			record.Name, err = cborStringField(label, entry)
+		case cborLabelUnit:
+			record.Unit, err = cborStringField(label, entry)
+		case cborLabelValue:
+			record.Value, err = cborFloatField(label, entry)
+		case cborLabelStringValue:
+			record.StringValue, err = cborStringField(label, entry)
+		case cborLabelBoolValue:
+			record.BoolValue, err = cborBoolField(label, entry)
+		case cborLabelSum:
+			record.Sum, err = cborFloatField(label, entry)
+		case cborLabelTime:
+			record.Time, err = cborFloatField(label, entry)
+		case cborLabelUpdateTime:
+			record.UpdateTime, err = cborFloatField(label, entry)
+		case cborLabelDataValue:
+			record.DataValue, err = cborDataField(label, entry)
+		case cborLabelBaseContentType:
+			record.BaseContentType, err = cborStringField(label, entry)
+		case cborLabelContentType:
+			record.ContentType, err = cborStringField(label, entry)
+		default:
+			if registration, ok := lookupLabelByCBORLabel(label); ok {
+				value, ok := registration.convert(entry.value)
+				if !ok {
+					err = newInvalidCBORError(entry.offset, fmt.Sprintf("the value of label %v has an unexpected type", label))
+					return
+				}
+				record.Extensions = addCBORExtension(record.Extensions, registration.name(), value)
+			} else {
+				record.Extensions = addCBORExtension(record.Extensions, strconv.FormatInt(label, 10), entry.value)
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// addCBORExtension adds the decoded value of an unknown label to the extensions
+func addCBORExtension(extensions map[string]interface{}, label string, value interface{}) map[string]interface{} {
+	if extensions == nil {
+		extensions = map[string]interface{}{}
+	}
+	extensions[label] = cborExtensionValue(value)
+	return extensions
+}
+
+// cborExtensionValue converts decoded maps to map[string]interface{}, integer keys are converted to their decimal representation
+func cborExtensionValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case []interface{}:
+		var items = make([]interface{}, len(value))
+		for i, item := range value {
+			items[i] = cborExtensionValue(item)
+		}
+		return items
+	case []cborMapEntry:
+		var entries = make(map[string]interface{}, len(value))
+		for _, entry := range value {
+			entries[fmt.Sprint(entry.key)] = cborExtensionValue(entry.value)
+		}
+		return entries
+	}
+	return value
+}
+
+func cborStringField(label int64, entry cborMapEntry) (*string, error) {
+	stringValue, ok := entry.value.(string)
+	if !ok {
+		return nil, newInvalidCBORError(entry.offset, fmt.Sprintf("the field with label %v is not a text string", label))
+	}
+	return &stringValue, nil
+}
+
+func cborFloatField(label int64, entry cborMapEntry) (*float64, error) {
+	var floatValue float64
+	switch number := entry.value.(type) {
+	case int64:
+		floatValue = float64(number)
+	case uint64:
+		floatValue = float64(number)
+	case float64:
+		floatValue = number
+	default:
+		return nil, newInvalidCBORError(entry.offset, fmt.Sprintf("the field with label %v is not a number", label))
+	}
+	return &floatValue, nil
+}
+
+func cborIntField(label int64, entry cborMapEntry) (*int, error) {
+	number, ok := entry.value.(int64)
+	if !ok || number < math.MinInt32 || number > math.MaxInt32 {
+		return nil, newInvalidCBORError(entry.offset, fmt.Sprintf("the field with label %v is not an integer", label))
+	}
+	var intValue = int(number)
+	return &intValue, nil
+}
+
+func cborBoolField(label int64, entry cborMapEntry) (*bool, error) {
+	boolValue, ok := entry.value.(bool)
+	if !ok {
+		return nil, newInvalidCBORError(entry.offset, fmt.Sprintf("the field with label %v is not a boolean", label))
+	}
+	return &boolValue, nil
+}
+
+func cborDataField(label int64, entry cborMapEntry) (*string, error) {
+	data, ok := entry.value.([]byte)
+	if !ok {
+		return nil, newInvalidCBORError(entry.offset, fmt.Sprintf("the field with label %v is not a byte string", label))
+	}
+	var record Record
+	record.SetDataValueBytes(data)
+	return record.DataValue, nil
+}
+
+// cborEncoder writes CBOR data items as defined in RFC 7049
+type cborEncoder struct {
+	buffer bytes.Buffer
+}
+
+func (encoder *cborEncoder) writeRecord(record Record) error {
+	var count uint64
+	for _, present := range []bool{
+		record.BaseVersion != nil, record.BaseName != nil, record.BaseTime != nil, record.BaseUnit != nil,
+		record.BaseValue != nil, record.BaseSum != nil, record.Name != nil, record.Unit != nil,
+		record.Value != nil, record.StringValue != nil, record.BoolValue != nil, record.Sum != nil,
+		record.Time != nil, record.UpdateTime != nil, record.DataValue != nil,
+		record.BaseContentType != nil, record.ContentType != nil,
+	} {
+		if present {
+			count++
+		}
+	}
+	count += uint64(len(record.Extensions))
+
+	data, err := record.DataValueBytes()
+	if err != nil {
+		return err
+	}
+
+	encoder.writeHead(cborMajorMap, count)
+	if record.BaseName != nil {
+		encoder.writeInt(cborLabelBaseName)
+		encoder.writeString(*record.BaseName)
+	}
+	if record.BaseTime != nil {
+		encoder.writeInt(cborLabelBaseTime)
+		encoder.writeFloat(*record.BaseTime)
+	}
+	if record.BaseUnit != nil {
+		encoder.writeInt(cborLabelBaseUnit)
+		encoder.writeString(*record.BaseUnit)
+	}
+	if record.BaseValue != nil {
+		encoder.writeInt(cborLabelBaseValue)
+		encoder.writeFloat(*record.BaseValue)
+	}
+	if record.BaseSum != nil {
+		encoder.writeInt(cborLabelBaseSum)
+		encoder.writeFloat(*record.BaseSum)
+	}
+	if record.BaseVersion != nil {
+		encoder.writeInt(cborLabelBaseVersion)
+		encoder.writeInt(int64(*record.BaseVersion))
+	}
+	if record.BaseContentType != nil {
+		encoder.writeInt(cborLabelBaseContentType)
+		encoder.writeString(*record.BaseContentType)
+	}
+	if record.Name != nil {
+		encoder.writeInt(cborLabelName)
+		encoder.writeString(*record.Name)
+	}
+	if record.Unit != nil {
+		encoder.writeInt(cborLabelUnit)
+		encoder.writeString(*record.Unit)
+	}
+	if record.Value != nil {
+		encoder.writeInt(cborLabelValue)
+		encoder.writeFloat(*record.Value)
+	}
+	if record.BoolValue != nil {
+		encoder.writeInt(cborLabelBoolValue)
+		encoder.writeBool(*record.BoolValue)
+	}
+	if record.StringValue != nil {
+		encoder.writeInt(cborLabelStringValue)
+		encoder.writeString(*record.StringValue)
+	}
+	if record.DataValue != nil {
+		encoder.writeInt(cborLabelDataValue)
+		encoder.writeBytes(data)
+	}
+	if record.Sum != nil {
+		encoder.writeInt(cborLabelSum)
+		encoder.writeFloat(*record.Sum)
+	}
+	if record.Time != nil {
+		encoder.writeInt(cborLabelTime)
+		encoder.writeFloat(*record.Time)
+	}
+	if record.UpdateTime != nil {
+		encoder.writeInt(cborLabelUpdateTime)
+		encoder.writeFloat(*record.UpdateTime)
+	}
+	if record.ContentType != nil {
+		encoder.writeInt(cborLabelContentType)
+		encoder.writeString(*record.ContentType)
+	}
+	for _, label := range sortedExtensionLabels(record.Extensions) {
+		if cborLabel, ok := lookupCBORLabel(label); ok {
+			encoder.writeInt(int64(cborLabel))
+		} else {
+			encoder.writeLabel(label)
+		}
+		if !encoder.writeValue(record.Extensions[label]) {
+			return newUnsupportedExtensionValueError(label, record.Extensions[label])
+		}
+	}
+	return nil
+}
+
+// writeLabel writes the label of an extension as an integer if it is the decimal representation of one, otherwise as a text string
+func (encoder *cborEncoder) writeLabel(label string) {
+	if intLabel, err := strconv.ParseInt(label, 10, 64); err == nil && strconv.FormatInt(intLabel, 10) == label {
+		encoder.writeInt(intLabel)
+	} else {
+		encoder.writeString(label)
+	}
+}
+
+// writeValue writes the value of an extension. It returns false if the type of the value is not supported.
+func (encoder *cborEncoder) writeValue(value interface{}) bool {
+	switch value := value.(type) {
+	case nil:
+		encoder.buffer.WriteByte(cborMajorSimple<<5 | 22)
+	case bool:
+		encoder.writeBool(value)
+	case int:
+		encoder.writeInt(int64(value))
+	case int64:
+		encoder.writeInt(value)
+	case uint64:
+		encoder.writeHead(cborMajorUnsignedInt, value)
+	case float64:
+		encoder.writeFloat(value)
+	case json.Number:
+		return encoder.writeNumber(value)
+	case string:
+		encoder.writeString(value)
+	case []byte:
+		encoder.writeBytes(value)
+	case []interface{}:
+		encoder.writeHead(cborMajorArray, uint64(len(value)))
+		for _, item := range value {
+			if !encoder.writeValue(item) {
+				return false
+			}
+		}
+	case map[string]interface{}:
+		encoder.writeHead(cborMajorMap, uint64(len(value)))
+		for _, key := range sortedExtensionLabels(value) {
+			encoder.writeLabel(key)
+			if !encoder.writeValue(value[key]) {
+				return false
+			}
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// writeNumber writes a JSON number as an integer if it is one, so integers beyond the precision of float64 are kept, otherwise as floating-point number.
+// It returns false if the value is not a number.
+func (encoder *cborEncoder) writeNumber(value json.Number) bool {
+	if intValue, err := strconv.ParseInt(value.String(), 10, 64); err == nil {
+		encoder.writeInt(intValue)
+		return true
+	}
+	if uintValue, err := strconv.ParseUint(value.String(), 10, 64); err == nil {
+		encoder.writeHead(cborMajorUnsignedInt, uintValue)
+		return true
+	}
+	floatValue, err := value.Float64()
+	if err != nil {
+		return false
+	}
+	encoder.writeFloat(floatValue)
+	return true
+}
+
+func (encoder *cborEncoder) writeHead(major byte, argument uint64) {
+	var head [9]byte
+	switch {
+	case argument < 24:
+		encoder.buffer.WriteByte(major<<5 | byte(argument))
+	case argument <= math.MaxUint8:
+		encoder.buffer.Write([]byte{major<<5 | 24, byte(argument)})
+	case argument <= math.MaxUint16:
+		head[0] = major<<5 | 25
+		binary.BigEndian.PutUint16(head[1:], uint16(argument))
+		encoder.buffer.Write(head[:3])
+	case argument <= math.MaxUint32:
+		head[0] = major<<5 | 26
+		binary.BigEndian.PutUint32(head[1:], uint32(argument))
+		encoder.buffer.Write(head[:5])
+	default:
+		head[0] = major<<5 | 27
+		binary.BigEndian.PutUint64(head[1:], argument)
+		encoder.buffer.Write(head[:9])
+	}
+}
+
+func (encoder *cborEncoder) writeInt(value int64) {
+	if value < 0 {
+		encoder.writeHead(cborMajorNegativeInt, uint64(-1-value))
+	} else {
+		encoder.writeHead(cborMajorUnsignedInt, uint64(value))
+	}
+}
+
+// writeFloat writes the value as an integer if it has no fractional part, otherwise it uses the shortest floating-point representation which doesn't lose precision.
+func (encoder *cborEncoder) writeFloat(value float64) {
+	if value == math.Trunc(value) && math.Abs(value) < 1<<53 {
+		encoder.writeInt(int64(value))
+		return
+	}
+	var head [9]byte
+	if float64(float32(value)) == value {
+		head[0] = cborMajorSimple<<5 | 26
+		binary.BigEndian.PutUint32(head[1:], math.Float32bits(float32(value)))
+		encoder.buffer.Write(head[:5])
+		return
+	}
+	head[0] = cborMajorSimple<<5 | 27
+	binary.BigEndian.PutUint64(head[1:], math.Float64bits(value))
+	encoder.buffer.Write(head[:9])
+}
+
+func (encoder *cborEncoder) writeString(value string) {
+	encoder.writeHead(cborMajorTextString, uint64(len(value)))
+	encoder.buffer.WriteString(value)
+}
+
+func (encoder *cborEncoder) writeBytes(value []byte) {
+	encoder.writeHead(cborMajorByteString, uint64(len(value)))
+	encoder.buffer.Write(value)
+}
+
+func (encoder *cborEncoder) writeBool(value bool) {
+	if value {
+		encoder.buffer.WriteByte(cborMajorSimple<<5 | 21)
+	} else {
+		encoder.buffer.WriteByte(cborMajorSimple<<5 | 20)
+	}
+}
+
+// cborDecoder reads CBOR data items as defined in RFC 7049.
+// Integers are returned as int64, floating-point numbers as float64, byte strings as []byte, text strings as string, arrays as []interface{} and maps as []cborMapEntry. Tags are skipped.
+type cborDecoder struct {
+	data   []byte
+	offset int
+}
+
+// cborBreak is returned by readItem when the "break" stop code of an indefinite length item was read
+type cborBreak struct{}
+
+func (decoder *cborDecoder) readItem(depth int) (interface{}, error) {
+	if depth > cborMaxDepth {
+		return nil, newInvalidCBORError(decoder.offset, "maximum nesting depth exceeded")
+	}
+	var start = decoder.offset
+	major, info, argument, err := decoder.readHead()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case cborMajorUnsignedInt:
+		if argument > math.MaxInt64 {
+			return argument, nil
+		}
+		return int64(argument), nil
+	case cborMajorNegativeInt:
+		if argument > math.MaxInt64 {
+			return nil, newInvalidCBORError(start, "integer overflow")
+		}
+		return -1 - int64(argument), nil
+	case cborMajorByteString, cborMajorTextString:
+		var value []byte
+		if info == cborIndefinite {
+			value, err = decoder.readChunks(major)
+		} else {
+			value, err = decoder.readBytes(argument)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if major == cborMajorTextString {
+			return string(value), nil
+		}
+		return value, nil
+	case cborMajorArray:
+		var items = []interface{}{}
+		for i := uint64(0); info == cborIndefinite || i < argument; i++ {
+			item, err := decoder.readItem(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if _, isBreak := item.(cborBreak); isBreak {
+				if info != cborIndefinite {
+					return nil, newInvalidCBORError(decoder.offset-1, "unexpected break")
+				}
+				break
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case cborMajorMap:
+		var entries = []cborMapEntry{}
+		for i := uint64(0); info == cborIndefinite || i < argument; i++ {
+			key, err := decoder.readItem(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if _, isBreak := key.(cborBreak); isBreak {
+				if info != cborIndefinite {
+					return nil, newInvalidCBORError(decoder.offset-1, "unexpected break")
+				}
+				break
+			}
+			var valueOffset = decoder.offset
+			value, err := decoder.readItem(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if _, isBreak := value.(cborBreak); isBreak {
+				return nil, newInvalidCBORError(decoder.offset-1, "unexpected break")
+			}
+			entries = append(entries, cborMapEntry{key: key, value: value, offset: valueOffset})
+		}
+		return entries, nil
+	case cborMajorTag:
+		return decoder.readItem(depth + 1)
+	default:
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		case 25:
+			return halfToFloat64(uint16(argument)), nil
+		case 26:
+			return float64(math.Float32frombits(uint32(argument))), nil
+		case 27:
+			return math.Float64frombits(argument), nil
+		case cborIndefinite:
+			return cborBreak{}, nil
+		default:
+			return nil, newInvalidCBORError(start, "unsupported simple value")
+		}
+	}
+}
+
+func (decoder *cborDecoder) readHead() (major byte, info byte, argument uint64, err error) {
+	if decoder.offset >= len(decoder.data) {
+		err = newInvalidCBORError(decoder.offset, "unexpected end of data")
+		return
+	}
+	var initial = decoder.data[decoder.offset]
+	decoder.offset++
+	major, info = initial>>5, initial&0x1f
+	switch {
+	case info < 24:
+		argument = uint64(info)
+	case info <= 27:
+		var size = 1 << (info - 24)
+		var bytes []byte
+		bytes, err = decoder.readBytes(uint64(size))
+		if err != nil {
+			return
+		}
+		for _, b := range bytes {
+			argument = argument<<8 | uint64(b)
+		}
+	case info == cborIndefinite:
+		if major == cborMajorUnsignedInt || major == cborMajorNegativeInt || major == cborMajorTag {
+			err = newInvalidCBORError(decoder.offset-1, "invalid indefinite length item")
+		}
+	default:
+		err = newInvalidCBORError(decoder.offset-1, "reserved additional information value")
+	}
+	return
+}
+
+func (decoder *cborDecoder) readBytes(length uint64) ([]byte, error) {
+	if length > uint64(len(decoder.data)-decoder.offset) {
+		return nil, newInvalidCBORError(decoder.offset, "unexpected end of data")
+	}
+	var value = decoder.data[decoder.offset : decoder.offset+int(length)]
+	decoder.offset += int(length)
+	return value, nil
+}
+
+// readChunks reads the definite length chunks of an indefinite length byte or text string
+func (decoder *cborDecoder) readChunks(major byte) ([]byte, error) {
+	var value []byte
+	for {
+		var start = decoder.offset
+		chunkMajor, info, argument, err := decoder.readHead()
+		if err != nil {
+			return nil, err
+		}
+		if chunkMajor == cborMajorSimple && info == cborIndefinite {
+			return value, nil
+		}
+		if chunkMajor != major || info == cborIndefinite {
+			return nil, newInvalidCBORError(start, "invalid chunk in indefinite length string")
+		}
+		chunk, err := decoder.readBytes(argument)
+		if err != nil {
+			return nil, err
+		}
+		value = append(value, chunk...)
+	}
+}
+
+// halfToFloat64 converts an IEEE 754 half-precision floating-point number to a float64
+func halfToFloat64(half uint16) float64 {
+	var exponent = int(half>>10) & 0x1f
+	var mantissa = float64(half & 0x3ff)
+	var value float64
+	switch exponent {
+	case 0:
+		value = math.Ldexp(mantissa, -24)
+	case 0x1f:
+		if mantissa == 0 {
+			value = math.Inf(1)
+		} else {
+			value = math.NaN()
+		}
+	default:
+		value = math.Ldexp(mantissa+1024, exponent-25)
+	}
+	if half&0x8000 != 0 {
+		return -value
+	}
+	return value
+}
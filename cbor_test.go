@@ -0,0 +1,214 @@
+package senml_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	senml "github.com/nkristek/go-senml"
+)
+
+// https://tools.ietf.org/html/rfc8428#section-6
+const cborHexData string = "82a421781c75726e3a6465763a6f773a31306532303733613031303830303633" +
+	"3a0067766f6c7461676501615602fb405e066666666666a3006763757272656e74" +
+	"01614102fb3ff3333333333333"
+
+func rfcCBORData(t *testing.T) []byte {
+	data, err := hex.DecodeString(cborHexData)
+	if err != nil {
+		t.Fatal("Decoding the hex test data failed: ", err)
+	}
+	return data
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	message, err := senml.Decode(rfcCBORData(t), senml.CBOR)
+	if err != nil {
+		t.Error("Decoding CBOR failed: ", err)
+		return
+	}
+
+	if len(message.Records) != 2 {
+		t.Error("The decoded message has an unexpected number of records")
+		return
+	}
+
+	var first = message.Records[0]
+	if first.BaseName == nil || *first.BaseName != "urn:dev:ow:10e2073a01080063:" {
+		t.Error("The base name was not decoded properly")
+	}
+	if first.Name == nil || *first.Name != "voltage" {
+		t.Error("The name was not decoded properly")
+	}
+	if first.Unit == nil || *first.Unit != "V" {
+		t.Error("The unit was not decoded properly")
+	}
+	if first.Value == nil || *first.Value != 120.1 {
+		t.Error("The value was not decoded properly")
+	}
+
+	var second = message.Records[1]
+	if second.Name == nil || *second.Name != "current" {
+		t.Error("The name was not decoded properly")
+	}
+	if second.Value == nil || *second.Value != 1.2 {
+		t.Error("The value was not decoded properly")
+	}
+}
+
+func TestEncodeCBOR(t *testing.T) {
+	message, err := senml.Decode([]byte(`[
+		{"bn":"urn:dev:ow:10e2073a01080063:","n":"voltage","u":"V","v":120.1},
+		{"n":"current","u":"A","v":1.2}
+	]`), senml.JSON)
+	if err != nil {
+		t.Error("Decoding JSON failed: ", err)
+		return
+	}
+
+	encodedMessage, err := message.Encode(senml.CBOR)
+	if err != nil {
+		t.Error("Encoding message to CBOR failed: ", err)
+		return
+	}
+
+	if !bytes.Equal(encodedMessage, rfcCBORData(t)) {
+		t.Errorf("Encoding to CBOR resulted in an unexpected message: %x", encodedMessage)
+	}
+}
+
+func TestEncodeDecodeCBORAllFields(t *testing.T) {
+	const jsonMessage = `[
+		{"bn":"urn:dev:ow:10e2073a01080063:","bt":1.320067464e+09,"bu":"%RH","bv":1.5,"bs":-100000,"bver":5,
+		 "n":"humidity","u":"%EL","v":20.25,"s":0.1,"t":-60,"ut":3600},
+		{"n":"label","vs":"Machine Room"},
+		{"n":"open","vb":false},
+		{"n":"nfc-reader","vd":"aGkgCg"}
+	]`
+	message, err := senml.Decode([]byte(jsonMessage), senml.JSON)
+	if err != nil {
+		t.Error("Decoding JSON failed: ", err)
+		return
+	}
+
+	encodedMessage, err := message.Encode(senml.CBOR)
+	if err != nil {
+		t.Error("Encoding message to CBOR failed: ", err)
+		return
+	}
+
+	decodedMessage, err := senml.Decode(encodedMessage, senml.CBOR)
+	if err != nil {
+		t.Error("Decoding CBOR failed: ", err)
+		return
+	}
+
+	expected, _ := message.Encode(senml.JSON)
+	actual, _ := decodedMessage.Encode(senml.JSON)
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("The message changed while encoding and decoding CBOR. expected: %s, got: %s", expected, actual)
+	}
+}
+
+func TestDecodeCBORDataValueIsByteString(t *testing.T) {
+	// [{0: "a", 8: h'686920 0a'}]
+	data, _ := hex.DecodeString("81a200616108446869200a")
+	message, err := senml.Decode(data, senml.CBOR)
+	if err != nil {
+		t.Error("Decoding CBOR failed: ", err)
+		return
+	}
+
+	if message.Records[0].DataValue == nil || *message.Records[0].DataValue != "aGkgCg" {
+		t.Error("The data value was not converted to base64url")
+	}
+}
+
+func TestDecodeCBORIndefiniteLengthAndTags(t *testing.T) {
+	// [_ {_ 0: (_ "te", "mp"), 2: 1(1.5 as half float), 6: 1(100)}, ]
+	data, _ := hex.DecodeString("9fbf007f627465626d70ff02c1f93e0006c11864ffff")
+	message, err := senml.Decode(data, senml.CBOR)
+	if err != nil {
+		t.Error("Decoding CBOR failed: ", err)
+		return
+	}
+
+	if len(message.Records) != 1 {
+		t.Error("The decoded message has an unexpected number of records")
+		return
+	}
+	var record = message.Records[0]
+	if record.Name == nil || *record.Name != "temp" {
+		t.Error("The indefinite length name was not decoded properly")
+	}
+	if record.Value == nil || *record.Value != 1.5 {
+		t.Error("The half-precision value was not decoded properly")
+	}
+	if record.Time == nil || *record.Time != 100 {
+		t.Error("The tagged integer time was not decoded properly")
+	}
+}
+
+func TestDecodeCBORInvalid(t *testing.T) {
+	var invalidMessages = map[string]string{
+		"empty":            "",
+		"not an array":     "a0",
+		"not a map":        "8101",
+		"truncated":        "81a1006574",
+		"trailing data":    "8000",
+		"wrong type":       "81a10001",
+		"data not bytes":   "81a1086161",
+		"version string":   "81a1206161",
+		"reserved info":    "1c",
+		"unexpected break": "81ff",
+	}
+	for name, invalidMessage := range invalidMessages {
+		data, _ := hex.DecodeString(invalidMessage)
+		_, err := senml.Decode(data, senml.CBOR)
+		if err == nil {
+			t.Errorf("Decoding invalid CBOR (%v) should result in an error", name)
+			continue
+		}
+		if _, ok := err.(*senml.InvalidCBORError); !ok {
+			t.Errorf("Decoding invalid CBOR (%v) resulted in an unexpected error type: %T", name, err)
+		}
+	}
+}
+
+func TestDecodeCBORNestingTooDeep(t *testing.T) {
+	var data = append(bytes.Repeat([]byte{0x81}, 100), 0x00)
+	_, err := senml.Decode(data, senml.CBOR)
+	if err == nil {
+		t.Error("Decoding deeply nested CBOR should result in an error")
+	}
+}
+
+func TestEncodeCBORInvalidDataValue(t *testing.T) {
+	var name = "test"
+	var dataValue = "not base64url!"
+	message := senml.Message{
+		Records: []senml.Record{
+			{
+				Name:      &name,
+				DataValue: &dataValue,
+			},
+		},
+	}
+
+	_, err := message.Encode(senml.CBOR)
+	if _, ok := err.(*senml.InvalidDataValueError); !ok {
+		t.Error("Encoding a data value which is not base64url encoded should result in an InvalidDataValueError, got: ", err)
+	}
+}
+
+func TestInvalidCBORError(t *testing.T) {
+	err := &senml.InvalidCBORError{
+		Offset: 1,
+		Reason: "reason",
+	}
+	message := err.Error()
+	if !strings.Contains(message, "reason") {
+		t.Error("The error message does not contain the reason.")
+	}
+}
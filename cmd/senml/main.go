@@ -0,0 +1,238 @@
+// Command senml converts, resolves, validates and prints SenML messages.
+//
+// Usage:
+//
+//	senml convert [--from json] [--to xml] [file]
+//	senml resolve [--from json] [--to json] [file]
+//	senml validate [--from json] [file]
+//	senml table [--from json] [file]
+//
+// The message is read from the file or from stdin if no file or "-" is given.
+// The formats are json, xml, cbor, exi and their SenSML variants sensml-json, sensml-xml, sensml-cbor and sensml-exi.
+package main
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	senml "github.com/nkristek/go-senml"
+)
+
+const (
+	exitSuccess = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+var formats = map[string]senml.EncodingFormat{
+	"json":        senml.JSON,
+	"xml":         senml.XML,
+	"cbor":        senml.CBOR,
+	"exi":         senml.EXI,
+	"sensml-json": senml.SenSMLJSON,
+	"sensml-xml":  senml.SenSMLXML,
+	"sensml-cbor": senml.SenSMLCBOR,
+	"sensml-exi":  senml.SenSMLEXI,
+}
+
+const usage = `Usage:
+  senml convert [--from json] [--to xml] [file]   convert the message to another format
+  senml resolve [--from json] [--to json] [file]  print the resolved records
+  senml validate [--from json] [file]             list all violations of the message
+  senml table [--from json] [file]                print the resolved records as a table
+
+The message is read from the file or from stdin if no file or "-" is given.
+Formats: json, xml, cbor, exi, sensml-json, sensml-xml, sensml-cbor, sensml-exi
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command with the arguments (without the program name) and returns the exit code
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+
+	var command = args[0]
+	var flags = flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+	}
+	var from = flags.String("from", "json", "the format of the input")
+	var to *string
+	switch command {
+	case "convert", "resolve":
+		to = flags.String("to", "json", "the format of the output")
+	case "validate", "table":
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitSuccess
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%v", command, usage)
+		return exitUsage
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() > 1 {
+		fmt.Fprintf(stderr, "expected at most one file, got %v\n", flags.NArg())
+		return exitUsage
+	}
+
+	inputFormat, err := parseFormat(*from)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	var outputFormat senml.EncodingFormat
+	if to != nil {
+		if outputFormat, err = parseFormat(*to); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitUsage
+		}
+	}
+
+	input, err := readInput(flags.Arg(0), stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+	message, err := senml.Decode(input, inputFormat)
+	if err != nil {
+		fmt.Fprintln(stderr, "decoding the message failed:", err)
+		return exitFailure
+	}
+
+	switch command {
+	case "convert":
+		err = encode(stdout, message, outputFormat)
+	case "resolve":
+		var resolvedMessage senml.Message
+		if resolvedMessage, err = message.Resolve(); err == nil {
+			err = encode(stdout, resolvedMessage, outputFormat)
+		}
+	case "validate":
+		return validate(stdout, stderr, message)
+	case "table":
+		err = table(stdout, message)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+	return exitSuccess
+}
+
+func parseFormat(name string) (senml.EncodingFormat, error) {
+	format, ok := formats[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown format %q", name)
+	}
+	return format, nil
+}
+
+// readInput reads the file or stdin if the path is empty or "-"
+func readInput(path string, stdin io.Reader) ([]byte, error) {
+	if path == "" || path == "-" {
+		return ioutil.ReadAll(stdin)
+	}
+	return ioutil.ReadFile(path)
+}
+
+// encode writes the encoded message. Text formats are terminated by a newline.
+func encode(stdout io.Writer, message senml.Message, format senml.EncodingFormat) error {
+	encodedMessage, err := message.Encode(format)
+	if err != nil {
+		return err
+	}
+	if format != senml.CBOR && format != senml.EXI && format != senml.SenSMLCBOR && format != senml.SenSMLEXI {
+		encodedMessage = append(encodedMessage, '\n')
+	}
+	_, err = stdout.Write(encodedMessage)
+	return err
+}
+
+// validate prints every violation of the message with the index of the record, the label of the field and the type of the error.
+// The exit code is exitFailure if the message is invalid.
+func validate(stdout io.Writer, stderr io.Writer, message senml.Message) int {
+	err := message.Validate()
+	if err == nil {
+		fmt.Fprintln(stdout, "valid")
+		return exitSuccess
+	}
+	validationError, ok := err.(*senml.ValidationError)
+	if !ok {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+	var writer = tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "RECORD\tFIELD\tERROR\tMESSAGE")
+	for _, recordError := range validationError.Errors {
+		fmt.Fprintf(writer, "%v\t%v\t%v\t%v\n", recordError.Index, recordError.Field, errorType(recordError.Err), recordError.Err)
+	}
+	writer.Flush()
+	return exitFailure
+}
+
+// errorType returns the name of the type of the error without the package, e.g. "InvalidNameError"
+func errorType(err error) string {
+	var name = fmt.Sprintf("%T", err)
+	name = strings.TrimPrefix(name, "*")
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// table prints the resolved records in chronological order as aligned columns with times in ISO 8601 format
+func table(stdout io.Writer, message senml.Message) error {
+	resolvedRecords, err := message.ResolveRecords()
+	if err != nil {
+		return err
+	}
+	var writer = tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "TIME\tNAME\tVALUE\tSUM\tUNIT")
+	for _, record := range resolvedRecords {
+		var recordTime, sum string
+		if !record.Time.IsZero() {
+			recordTime = record.Time.Format(time.RFC3339Nano)
+		}
+		if record.HasSum {
+			sum = formatFloat(record.Sum)
+		}
+		fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\n", recordTime, record.Name, formatValue(record.Value), sum, record.Unit)
+	}
+	return writer.Flush()
+}
+
+func formatValue(value senml.Value) string {
+	if number, ok := value.AsFloat(); ok {
+		return formatFloat(number)
+	}
+	if boolValue, ok := value.AsBool(); ok {
+		return strconv.FormatBool(boolValue)
+	}
+	if stringValue, ok := value.AsString(); ok {
+		return strconv.Quote(stringValue)
+	}
+	if data, ok := value.AsData(); ok {
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	return ""
+}
+
+func formatFloat(number float64) string {
+	return strconv.FormatFloat(number, 'g', -1, 64)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	senml "github.com/nkristek/go-senml"
+)
+
+const testData = `[{"bn":"urn:dev:ow:10e2073a01080063:","bt":1.320067464e+09,"bu":"%RH","v":20},{"u":"lon","v":24.30621},{"u":"lat","v":60.07965},{"t":60,"v":20.3},{"n":"door","vb":true,"t":60}]`
+
+func runCommand(input string, args ...string) (exitCode int, stdout string, stderr string) {
+	var stdoutBuffer, stderrBuffer bytes.Buffer
+	exitCode = run(args, strings.NewReader(input), &stdoutBuffer, &stderrBuffer)
+	return exitCode, stdoutBuffer.String(), stderrBuffer.String()
+}
+
+func TestConvert(t *testing.T) {
+	exitCode, stdout, stderr := runCommand(testData, "convert", "--from", "json", "--to", "xml")
+	if exitCode != exitSuccess {
+		t.Errorf("Converting the message failed with exit code %v: %v", exitCode, stderr)
+		return
+	}
+	message, err := senml.Decode([]byte(stdout), senml.XML)
+	if err != nil {
+		t.Error("The converted message can't be decoded: ", err)
+		return
+	}
+	if len(message.Records) != 5 {
+		t.Errorf("The converted message contains %v records, expected 5", len(message.Records))
+	}
+
+	exitCode, stdout, stderr = runCommand(stdout, "convert", "--from", "xml", "--to", "cbor")
+	if exitCode != exitSuccess {
+		t.Errorf("Converting the message failed with exit code %v: %v", exitCode, stderr)
+		return
+	}
+	if _, err := senml.Decode([]byte(stdout), senml.CBOR); err != nil {
+		t.Error("The converted message can't be decoded: ", err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	exitCode, stdout, stderr := runCommand(testData, "resolve")
+	if exitCode != exitSuccess {
+		t.Errorf("Resolving the message failed with exit code %v: %v", exitCode, stderr)
+		return
+	}
+	message, err := senml.Decode([]byte(stdout), senml.JSON)
+	if err != nil {
+		t.Error("The resolved message can't be decoded: ", err)
+		return
+	}
+	var first = message.Records[0]
+	if first.Name == nil || *first.Name != "urn:dev:ow:10e2073a01080063:" || first.Time == nil || *first.Time != 1320067464 {
+		t.Errorf("The message was not resolved: %s", stdout)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	exitCode, stdout, _ := runCommand(testData, "validate")
+	if exitCode != exitSuccess || !strings.Contains(stdout, "valid") {
+		t.Errorf("Validating a valid message should succeed, got exit code %v: %v", exitCode, stdout)
+	}
+
+	exitCode, stdout, _ = runCommand(`[{"n":"-temp","v":1},{"n":"door"}]`, "validate")
+	if exitCode != exitFailure {
+		t.Errorf("Validating an invalid message should fail, got exit code %v", exitCode)
+	}
+	var lines = strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 3 {
+		t.Errorf("Validating the message should list two violations: %v", stdout)
+		return
+	}
+	if !strings.HasPrefix(lines[1], "0 ") || !strings.Contains(lines[1], "InvalidNameError") || !strings.HasPrefix(lines[2], "1 ") || !strings.Contains(lines[2], "MissingValueError") {
+		t.Errorf("The violations are not listed with their record and error type: %v", stdout)
+	}
+}
+
+func TestTable(t *testing.T) {
+	exitCode, stdout, stderr := runCommand(testData, "table")
+	if exitCode != exitSuccess {
+		t.Errorf("Printing the table failed with exit code %v: %v", exitCode, stderr)
+		return
+	}
+	var lines = strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 6 {
+		t.Errorf("The table should contain a header and 5 records: %v", stdout)
+		return
+	}
+	var valueColumn = strings.Index(lines[0], "VALUE")
+	for _, line := range lines[1:] {
+		if !strings.HasPrefix(line, "2011-10-31T13:2") {
+			t.Errorf("The time is not in ISO 8601 format: %v", line)
+		}
+		if line[valueColumn-1] != ' ' || line[valueColumn] == ' ' {
+			t.Errorf("The columns are not aligned: %v", line)
+		}
+	}
+	if !strings.Contains(lines[5], "2011-10-31T13:25:24Z") || !strings.Contains(lines[5], "true") {
+		t.Errorf("The records are not sorted chronologically: %v", stdout)
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	directory, err := ioutil.TempDir("", "senml")
+	if err != nil {
+		t.Error("Creating a temporary directory failed: ", err)
+		return
+	}
+	defer os.RemoveAll(directory)
+	var path = filepath.Join(directory, "message.json")
+	if err := ioutil.WriteFile(path, []byte(testData), 0600); err != nil {
+		t.Error("Writing the file failed: ", err)
+		return
+	}
+
+	exitCode, stdout, stderr := runCommand("", "convert", "--to", "sensml-json", path)
+	if exitCode != exitSuccess {
+		t.Errorf("Converting the file failed with exit code %v: %v", exitCode, stderr)
+		return
+	}
+	if strings.Count(stdout, "\n") != 7 {
+		t.Errorf("The SenSML stream should contain every record on its own line: %v", stdout)
+	}
+
+	exitCode, _, _ = runCommand("", "convert", filepath.Join(directory, "missing.json"))
+	if exitCode != exitFailure {
+		t.Errorf("Reading a missing file should fail, got exit code %v", exitCode)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"unknown"},
+		{"convert", "--to", "yaml"},
+		{"validate", "--to", "xml"},
+		{"table", "first.json", "second.json"},
+	} {
+		exitCode, _, stderr := runCommand(testData, args...)
+		if exitCode != exitUsage || stderr == "" {
+			t.Errorf("Running %v should fail with a usage error, got exit code %v", args, exitCode)
+		}
+	}
+
+	exitCode, _, stderr := runCommand("not json", "resolve")
+	if exitCode != exitFailure || !strings.Contains(stderr, "decoding") {
+		t.Errorf("Decoding an invalid message should fail, got exit code %v: %v", exitCode, stderr)
+	}
+}
@@ -0,0 +1,151 @@
+package senml
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+// Compact is the inverse of Resolve. It moves attributes which are common to all records into base attributes on the first record:
+// the longest common prefix of the names becomes the BaseName, the most common unit the BaseUnit and the earliest time the BaseTime.
+// Times, values and sums are replaced by offsets to their base attribute if this results in a shorter representation.
+// The message is expected to be resolved. Resolving the compacted message results in the same records.
+func (message Message) Compact() (compactedMessage Message) {
+	if len(message.Records) == 0 {
+		return message
+	}
+	compactedMessage.XMLName = message.XMLName
+	compactedMessage.Stream = message.Stream
+	compactedMessage.Records = make([]Record, len(message.Records))
+	copy(compactedMessage.Records, message.Records)
+	var records = compactedMessage.Records
+	var first = &records[0]
+
+	if baseName, ok := commonNamePrefix(records); ok {
+		first.BaseName = &baseName
+		for i := range records {
+			var name = (*records[i].Name)[len(baseName):]
+			if len(name) == 0 {
+				records[i].Name = nil
+			} else {
+				records[i].Name = &name
+			}
+		}
+	}
+
+	if baseUnit, ok := mostCommonUnit(records); ok {
+		first.BaseUnit = &baseUnit
+		for i := range records {
+			if *records[i].Unit == baseUnit {
+				records[i].Unit = nil
+			}
+		}
+	}
+
+	first.BaseTime = compactField(records, func(record *Record) **float64 { return &record.Time })
+	first.BaseValue = compactField(records, func(record *Record) **float64 { return &record.Value })
+	first.BaseSum = compactField(records, func(record *Record) **float64 { return &record.Sum })
+
+	if hasSameBaseVersion(records) {
+		for i := 1; i < len(records); i++ {
+			records[i].BaseVersion = nil
+		}
+	}
+	return
+}
+
+// hasSameBaseVersion returns true if all records have the same BaseVersion set
+func hasSameBaseVersion(records []Record) bool {
+	for _, record := range records {
+		if record.BaseVersion == nil || *record.BaseVersion != *records[0].BaseVersion {
+			return false
+		}
+	}
+	return true
+}
+
+// commonNamePrefix returns the longest common prefix of the names of all records. It returns false if at least one record has no name or the prefix is empty.
+func commonNamePrefix(records []Record) (string, bool) {
+	if records[0].Name == nil {
+		return "", false
+	}
+	var prefix = *records[0].Name
+	for _, record := range records[1:] {
+		if record.Name == nil {
+			return "", false
+		}
+		var length = 0
+		for length < len(prefix) && length < len(*record.Name) && prefix[length] == (*record.Name)[length] {
+			length++
+		}
+		prefix = prefix[:length]
+	}
+	// don't split a multi-byte character
+	var length = len(prefix)
+	for length > 0 && length < len(*records[0].Name) && !utf8.RuneStart((*records[0].Name)[length]) {
+		length--
+	}
+	prefix = prefix[:length]
+	return prefix, len(prefix) > 0
+}
+
+// mostCommonUnit returns the unit which is used by most records. It returns false if at least one record has no unit, since it would inherit the base unit otherwise.
+func mostCommonUnit(records []Record) (string, bool) {
+	var counts = map[string]int{}
+	var mostCommon string
+	for _, record := range records {
+		if record.Unit == nil {
+			return "", false
+		}
+		counts[*record.Unit]++
+		if counts[*record.Unit] > counts[mostCommon] {
+			mostCommon = *record.Unit
+		}
+	}
+	return mostCommon, true
+}
+
+// compactField replaces the field of every record with its offset to the smallest value and returns the smallest value as the base attribute.
+// It returns nil and doesn't change the records if at least one record has no value for the field, if the offsets can't be represented without losing precision or if the result is not shorter.
+func compactField(records []Record, field func(record *Record) **float64) *float64 {
+	var base float64
+	for i := range records {
+		var value = *field(&records[i])
+		if value == nil {
+			return nil
+		}
+		if i == 0 || *value < base {
+			base = *value
+		}
+	}
+
+	var originalLength, compactedLength = 0, len(formatFloat(base))
+	var offsets = make([]float64, len(records))
+	for i := range records {
+		var value = **field(&records[i])
+		offsets[i] = value - base
+		if base+offsets[i] != value {
+			return nil
+		}
+		originalLength += len(formatFloat(value))
+		if offsets[i] != 0 {
+			compactedLength += len(formatFloat(offsets[i]))
+		}
+	}
+	if compactedLength >= originalLength {
+		return nil
+	}
+
+	for i := range records {
+		if offsets[i] == 0 {
+			*field(&records[i]) = nil
+		} else {
+			var offset = offsets[i]
+			*field(&records[i]) = &offset
+		}
+	}
+	return &base
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,147 @@
+package senml_test
+
+import (
+	"reflect"
+	"testing"
+
+	senml "github.com/nkristek/go-senml"
+)
+
+func TestCompactResolvesToSameRecords(t *testing.T) {
+	for _, data := range []struct {
+		format  senml.EncodingFormat
+		message string
+	}{
+		{senml.JSON, jsonData},
+		{senml.XML, xmlData},
+	} {
+		message, err := senml.Decode([]byte(data.message), data.format)
+		if err != nil {
+			t.Error("Decoding failed: ", err)
+			return
+		}
+		resolvedMessage, err := message.Resolve()
+		if err != nil {
+			t.Error("Resolving the message failed: ", err)
+			return
+		}
+
+		var compactedMessage = resolvedMessage.Compact()
+		resolvedCompactedMessage, err := compactedMessage.Resolve()
+		if err != nil {
+			t.Error("Resolving the compacted message failed: ", err)
+			return
+		}
+
+		if !reflect.DeepEqual(resolvedMessage.Records, resolvedCompactedMessage.Records) {
+			t.Error("The compacted message does not resolve to the same records")
+		}
+
+		resolvedJSON, _ := resolvedMessage.Encode(senml.JSON)
+		compactedJSON, _ := compactedMessage.Encode(senml.JSON)
+		if len(compactedJSON) >= len(resolvedJSON) {
+			t.Error("The compacted message is not smaller than the resolved message")
+		}
+	}
+}
+
+func TestCompactBaseAttributes(t *testing.T) {
+	var names = []string{"urn:dev:ow:10e2073a01080063:temp", "urn:dev:ow:10e2073a01080063:humidity", "urn:dev:ow:10e2073a01080063:temp"}
+	var units = []string{"Cel", "%RH", "Cel"}
+	var times = []float64{1320067464, 1320067524, 1320067584}
+	var values = []float64{20, 80, 21}
+	var version = 5
+	var message senml.Message
+	for i := range names {
+		message.Records = append(message.Records, senml.Record{
+			BaseVersion: &version,
+			Name:        &names[i],
+			Unit:        &units[i],
+			Time:        &times[i],
+			Value:       &values[i],
+		})
+	}
+
+	var compactedMessage = message.Compact()
+	var first = compactedMessage.Records[0]
+	if first.BaseName == nil || *first.BaseName != "urn:dev:ow:10e2073a01080063:" {
+		t.Error("The common name prefix was not used as base name")
+	}
+	if first.BaseUnit == nil || *first.BaseUnit != "Cel" {
+		t.Error("The most common unit was not used as base unit")
+	}
+	if first.BaseTime == nil || *first.BaseTime != times[0] || first.Time != nil {
+		t.Error("The earliest time was not used as base time")
+	}
+	if first.BaseVersion == nil {
+		t.Error("The base version was removed from the first record")
+	}
+
+	var second = compactedMessage.Records[1]
+	if second.Name == nil || *second.Name != "humidity" {
+		t.Error("The name is not relative to the base name")
+	}
+	if second.Unit == nil || *second.Unit != "%RH" {
+		t.Error("A unit which differs from the base unit was removed")
+	}
+	if second.Time == nil || *second.Time != 60 {
+		t.Error("The time is not relative to the base time")
+	}
+	if second.BaseVersion != nil {
+		t.Error("The base version was not removed from the following records")
+	}
+
+	if message.Records[1].Name == nil || *message.Records[1].Name != names[1] {
+		t.Error("The original message was modified")
+	}
+}
+
+func TestCompactKeepsFieldsWhichAreNotSetOnAllRecords(t *testing.T) {
+	var name = "test"
+	var unit = "Cel"
+	var time float64 = 1320067464
+	var value float64 = 1
+	var boolValue = true
+	message := senml.Message{
+		Records: []senml.Record{
+			{Name: &name, Unit: &unit, Time: &time, Value: &value},
+			{Name: &name, BoolValue: &boolValue},
+		},
+	}
+
+	var compactedMessage = message.Compact()
+	var first = compactedMessage.Records[0]
+	if first.BaseUnit != nil || first.BaseTime != nil || first.BaseValue != nil {
+		t.Error("A base attribute was set although not all records have the field set")
+	}
+	if first.Unit == nil || first.Time == nil || first.Value == nil {
+		t.Error("A field was removed although no base attribute was set")
+	}
+	if first.BaseName == nil || *first.BaseName != name || first.Name != nil {
+		t.Error("The identical names were not moved into the base name")
+	}
+}
+
+func TestCompactDoesNotSplitCharacters(t *testing.T) {
+	var first = "sensor/ä"
+	var second = "sensor/ö"
+	var value float64 = 1
+	message := senml.Message{
+		Records: []senml.Record{
+			{Name: &first, Value: &value},
+			{Name: &second, Value: &value},
+		},
+	}
+
+	var compactedMessage = message.Compact()
+	if compactedMessage.Records[0].BaseName == nil || *compactedMessage.Records[0].BaseName != "sensor/" {
+		t.Error("The base name does not end at a character boundary")
+	}
+}
+
+func TestCompactEmpty(t *testing.T) {
+	var compactedMessage = senml.Message{}.Compact()
+	if len(compactedMessage.Records) != 0 {
+		t.Error("Compacting an empty message should result in an empty message")
+	}
+}
@@ -0,0 +1,301 @@
+package senml_test
+
+import (
+	"encoding/hex"
+	"encoding/xml"
+	"reflect"
+	"testing"
+	"time"
+
+	senml "github.com/nkristek/go-senml"
+)
+
+// conformanceExamples are the examples of RFC 8428 chapters 5, 6 and 7 together with their resolved records in JSON.
+var conformanceExamples = []struct {
+	name     string
+	format   senml.EncodingFormat
+	data     string
+	resolved string
+}{
+	{
+		// https://tools.ietf.org/html/rfc8428#section-5.1.1
+		name:   "single datapoint",
+		format: senml.JSON,
+		data: `[
+			{"n":"urn:dev:ow:10e2073a01080063","u":"Cel","v":23.1}
+		]`,
+		resolved: `[
+			{"n":"urn:dev:ow:10e2073a01080063","u":"Cel","v":23.1}
+		]`,
+	},
+	{
+		// https://tools.ietf.org/html/rfc8428#section-5.1.2
+		name:   "multiple datapoints",
+		format: senml.JSON,
+		data: `[
+			{"bn":"urn:dev:ow:10e2073a01080063:","n":"voltage","u":"V","v":120.1},
+			{"n":"current","u":"A","v":1.2}
+		]`,
+		resolved: `[
+			{"n":"urn:dev:ow:10e2073a01080063:voltage","u":"V","v":120.1},
+			{"n":"urn:dev:ow:10e2073a01080063:current","u":"A","v":1.2}
+		]`,
+	},
+	{
+		// https://tools.ietf.org/html/rfc8428#section-5.1.2
+		name:   "multiple datapoints with relative times",
+		format: senml.JSON,
+		data: `[
+			{"bn":"urn:dev:ow:10e2073a0108006:","bt":1.276020076001e+09,
+			 "bu":"A","bver":5,
+			 "n":"voltage","u":"V","v":120.1},
+			{"n":"current","t":-5,"v":1.2},
+			{"n":"current","t":-4,"v":1.3},
+			{"n":"current","t":-3,"v":1.4},
+			{"n":"current","t":-2,"v":1.5},
+			{"n":"current","t":-1,"v":1.6},
+			{"n":"current","v":1.7}
+		]`,
+		// https://tools.ietf.org/html/rfc8428#section-5.1.4
+		resolved: `[
+			{"n":"urn:dev:ow:10e2073a0108006:voltage","u":"V","v":120.1,
+			 "t":1.276020076001e+09},
+			{"n":"urn:dev:ow:10e2073a0108006:current","u":"A","v":1.2,
+			 "t":1.276020071001e+09},
+			{"n":"urn:dev:ow:10e2073a0108006:current","u":"A","v":1.3,
+			 "t":1.276020072001e+09},
+			{"n":"urn:dev:ow:10e2073a0108006:current","u":"A","v":1.4,
+			 "t":1.276020073001e+09},
+			{"n":"urn:dev:ow:10e2073a0108006:current","u":"A","v":1.5,
+			 "t":1.276020074001e+09},
+			{"n":"urn:dev:ow:10e2073a0108006:current","u":"A","v":1.6,
+			 "t":1.276020075001e+09},
+			{"n":"urn:dev:ow:10e2073a0108006:current","u":"A","v":1.7,
+			 "t":1.276020076001e+09}
+		]`,
+	},
+	{
+		// https://tools.ietf.org/html/rfc8428#section-5.1.3
+		name:   "multiple measurements with location",
+		format: senml.JSON,
+		data:   jsonData,
+		resolved: `[
+			{"n":"urn:dev:ow:10e2073a01080063","u":"%RH","t":1.320067464e+09,"v":20},
+			{"n":"urn:dev:ow:10e2073a01080063","u":"lon","t":1.320067464e+09,"v":24.30621},
+			{"n":"urn:dev:ow:10e2073a01080063","u":"lat","t":1.320067464e+09,"v":60.07965},
+			{"n":"urn:dev:ow:10e2073a01080063","u":"%RH","t":1.320067524e+09,"v":20.3},
+			{"n":"urn:dev:ow:10e2073a01080063","u":"lon","t":1.320067524e+09,"v":24.30622},
+			{"n":"urn:dev:ow:10e2073a01080063","u":"lat","t":1.320067524e+09,"v":60.07965},
+			{"n":"urn:dev:ow:10e2073a01080063","u":"%RH","t":1.320067584e+09,"v":20.7},
+			{"n":"urn:dev:ow:10e2073a01080063","u":"lon","t":1.320067584e+09,"v":24.30623},
+			{"n":"urn:dev:ow:10e2073a01080063","u":"lat","t":1.320067584e+09,"v":60.07966},
+			{"n":"urn:dev:ow:10e2073a01080063","u":"%EL","t":1.320067614e+09,"v":98},
+			{"n":"urn:dev:ow:10e2073a01080063","u":"%RH","t":1.320067644e+09,"v":21.2},
+			{"n":"urn:dev:ow:10e2073a01080063","u":"lon","t":1.320067644e+09,"v":24.30628},
+			{"n":"urn:dev:ow:10e2073a01080063","u":"lat","t":1.320067644e+09,"v":60.07967}
+		]`,
+	},
+	{
+		// https://tools.ietf.org/html/rfc8428#section-5.1.5
+		name:   "multiple data types",
+		format: senml.JSON,
+		data: `[
+			{"bn":"urn:dev:ow:10e2073a01080063:","n":"temperature","u":"Cel",
+			 "v":23.1},
+			{"n":"label","vs":"Machine Room"},
+			{"n":"open","vb":false},
+			{"n":"nfc-reader","vd":"aGkgCg"}
+		]`,
+		resolved: `[
+			{"n":"urn:dev:ow:10e2073a01080063:temperature","u":"Cel","v":23.1},
+			{"n":"urn:dev:ow:10e2073a01080063:label","vs":"Machine Room"},
+			{"n":"urn:dev:ow:10e2073a01080063:open","vb":false},
+			{"n":"urn:dev:ow:10e2073a01080063:nfc-reader","vd":"aGkgCg"}
+		]`,
+	},
+	{
+		// https://tools.ietf.org/html/rfc8428#section-5.1.6
+		name:   "collection of resources",
+		format: senml.JSON,
+		data: `[
+			{"bn":"2001:db8::2/","bt":1.320078429e+09,
+			 "n":"temperature","u":"Cel","v":27.2},
+			{"n":"humidity","u":"%RH","v":80}
+		]`,
+		resolved: `[
+			{"n":"2001:db8::2/temperature","u":"Cel","t":1.320078429e+09,"v":27.2},
+			{"n":"2001:db8::2/humidity","u":"%RH","t":1.320078429e+09,"v":80}
+		]`,
+	},
+	{
+		// https://tools.ietf.org/html/rfc8428#section-5.1.7
+		// The first record only contains the base name, so it doesn't resolve to a record on its own.
+		name:   "setting an actuator",
+		format: senml.JSON,
+		data: `[
+			{"bn":"urn:dev:ow:10e2073a01080063:"},
+			{"n":"temp","u":"Cel","v":23.1},
+			{"n":"heat","u":"/","v":1},
+			{"n":"fan","u":"/","v":0}
+		]`,
+		resolved: `[
+			{"n":"urn:dev:ow:10e2073a01080063:temp","u":"Cel","v":23.1},
+			{"n":"urn:dev:ow:10e2073a01080063:heat","u":"/","v":1},
+			{"n":"urn:dev:ow:10e2073a01080063:fan","u":"/","v":0}
+		]`,
+	},
+	{
+		// https://tools.ietf.org/html/rfc8428#section-5.1.7
+		name:   "turning on lights",
+		format: senml.JSON,
+		data: `[
+			{"bt":1.320078429e+09,"bu":"/","n":"2001:db8::3","v":1},
+			{"n":"2001:db8::4","v":1}
+		]`,
+		resolved: `[
+			{"n":"2001:db8::3","u":"/","t":1.320078429e+09,"v":1},
+			{"n":"2001:db8::4","u":"/","t":1.320078429e+09,"v":1}
+		]`,
+	},
+	{
+		// https://tools.ietf.org/html/rfc8428#section-6
+		name:   "CBOR representation",
+		format: senml.CBOR,
+		data:   cborHexData,
+		resolved: `[
+			{"n":"urn:dev:ow:10e2073a01080063:voltage","u":"V","v":120.1},
+			{"n":"urn:dev:ow:10e2073a01080063:current","u":"A","v":1.2}
+		]`,
+	},
+	{
+		// https://tools.ietf.org/html/rfc8428#section-7
+		name:   "XML representation",
+		format: senml.XML,
+		data:   xmlData,
+		resolved: `[
+			{"n":"urn:dev:ow:10e2073a0108006:voltage","u":"V","v":120.1,"t":1.276020076001e+09},
+			{"n":"urn:dev:ow:10e2073a0108006:current","u":"A","v":1.2,"t":1.276020071001e+09},
+			{"n":"urn:dev:ow:10e2073a0108006:current","u":"A","v":1.3,"t":1.276020072001e+09},
+			{"n":"urn:dev:ow:10e2073a0108006:current","u":"A","v":1.4,"t":1.276020073001e+09},
+			{"n":"urn:dev:ow:10e2073a0108006:current","u":"A","v":1.5,"t":1.276020074001e+09},
+			{"n":"urn:dev:ow:10e2073a0108006:current","u":"A","v":1.6,"t":1.276020075001e+09},
+			{"n":"urn:dev:ow:10e2073a0108006:current","u":"A","v":1.7,"t":1.276020076001e+09}
+		]`,
+	},
+}
+
+func conformanceExampleData(t *testing.T, format senml.EncodingFormat, data string) []byte {
+	if format == senml.CBOR {
+		decodedData, err := hex.DecodeString(data)
+		if err != nil {
+			t.Fatal("Decoding the hex test data failed: ", err)
+		}
+		return decodedData
+	}
+	return []byte(data)
+}
+
+func TestConformanceResolve(t *testing.T) {
+	for _, example := range conformanceExamples {
+		message, err := senml.Decode(conformanceExampleData(t, example.format, example.data), example.format)
+		if err != nil {
+			t.Errorf("Decoding the example %q failed: %v", example.name, err)
+			continue
+		}
+		if err := message.Validate(); err != nil {
+			t.Errorf("The example %q is not valid: %v", example.name, err)
+			continue
+		}
+
+		resolvedMessage, err := message.ResolveWithOptions(senml.ResolveOptions{
+			ReferenceTime:       time.Unix(0, 0),
+			KeepOrder:           true,
+			SkipVersionStamping: true,
+		})
+		if err != nil {
+			t.Errorf("Resolving the example %q failed: %v", example.name, err)
+			continue
+		}
+
+		expectedMessage, err := senml.Decode([]byte(example.resolved), senml.JSON)
+		if err != nil {
+			t.Errorf("Decoding the resolved records of the example %q failed: %v", example.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(expectedMessage.Records, withoutXMLNames(resolvedMessage.Records)) {
+			resolvedJSON, _ := resolvedMessage.Encode(senml.JSON)
+			t.Errorf("The example %q resolved to unexpected records: %s", example.name, resolvedJSON)
+		}
+	}
+}
+
+// withoutXMLNames returns a copy of the records without the XMLName field, which is only set when decoding XML
+func withoutXMLNames(records []senml.Record) []senml.Record {
+	var copiedRecords = make([]senml.Record, len(records))
+	for i, record := range records {
+		record.XMLName = xml.Name{}
+		copiedRecords[i] = record
+	}
+	return copiedRecords
+}
+
+func TestConformanceRoundTrip(t *testing.T) {
+	for _, example := range conformanceExamples {
+		message, err := senml.Decode(conformanceExampleData(t, example.format, example.data), example.format)
+		if err != nil {
+			t.Errorf("Decoding the example %q failed: %v", example.name, err)
+			continue
+		}
+
+		for _, format := range []senml.EncodingFormat{senml.JSON, senml.XML, senml.CBOR, senml.EXI} {
+			encodedMessage, err := message.Encode(format)
+			if err != nil {
+				t.Errorf("Encoding the example %q to format %v failed: %v", example.name, format, err)
+				continue
+			}
+			decodedMessage, err := senml.Decode(encodedMessage, format)
+			if err != nil {
+				t.Errorf("Decoding the encoded example %q in format %v failed: %v", example.name, format, err)
+				continue
+			}
+			if !reflect.DeepEqual(withoutXMLNames(message.Records), withoutXMLNames(decodedMessage.Records)) {
+				t.Errorf("The records of the example %q changed while encoding and decoding format %v", example.name, format)
+			}
+		}
+	}
+}
+
+func TestConformanceBaseValueAndBaseSum(t *testing.T) {
+	const data = `[{"bn":"urn:dev:ow:10e2073a01080063:","bv":20,"bs":100,"n":"temperature","v":1.5,"s":50}]`
+	message, err := senml.Decode([]byte(data), senml.JSON)
+	if err != nil {
+		t.Error("Decoding JSON failed: ", err)
+		return
+	}
+	if message.Records[0].BaseValue == nil || *message.Records[0].BaseValue != 20 {
+		t.Error("The base value was not decoded")
+		return
+	}
+	if message.Records[0].BaseSum == nil || *message.Records[0].BaseSum != 100 {
+		t.Error("The base sum was not decoded")
+		return
+	}
+
+	encodedMessage, err := message.Encode(senml.JSON)
+	if err != nil {
+		t.Error("Encoding JSON failed: ", err)
+		return
+	}
+	if string(encodedMessage) != data {
+		t.Errorf("Encoding JSON resulted in an unexpected message. expected: %s, got: %s", data, encodedMessage)
+	}
+
+	resolvedMessage, err := message.Resolve()
+	if err != nil {
+		t.Error("Resolving the message failed: ", err)
+		return
+	}
+	if *resolvedMessage.Records[0].Value != 21.5 || *resolvedMessage.Records[0].Sum != 150 {
+		t.Error("The base value and base sum were not added to the value and sum")
+	}
+}
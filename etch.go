@@ -0,0 +1,167 @@
+package senml
+
+import (
+	"fmt"
+)
+
+// InvalidFetchRecordError is an error which is returned when a record of a FETCH document contains other fields than the name and time,
+// which are the only fields used to select records (RFC 8790 chapter 4.2).
+type InvalidFetchRecordError struct {
+	// The index of the fetch record
+	Index int
+
+	// The label of the field which is not allowed
+	Field string
+}
+
+func (err *InvalidFetchRecordError) Error() string {
+	return fmt.Sprintf("The fetch record at index %v contains the field %q. Fetch records may only contain the name, time and version", err.Index, err.Field)
+}
+
+func newInvalidFetchRecordError(index int, field string) *InvalidFetchRecordError {
+	return &InvalidFetchRecordError{
+		Index: index,
+		Field: field,
+	}
+}
+
+// NewFetch returns a FETCH document which selects the records with the given resolved names.
+func NewFetch(names ...string) Message {
+	var fetch Message
+	for _, name := range names {
+		var fetchName = name
+		fetch.Records = append(fetch.Records, Record{Name: &fetchName})
+	}
+	return fetch
+}
+
+// DecodeFetch parses a FETCH document (application/senml-etch+json or application/senml-etch+cbor) with the given format.
+// Only JSON and CBOR are defined for FETCH documents (RFC 8790 chapter 6). Returns an InvalidFetchRecordError if a record contains a value or other fields which can't be used to select records.
+func DecodeFetch(encodedFetch []byte, format EncodingFormat) (Message, error) {
+	if format != JSON && format != CBOR {
+		return Message{}, newUnsupportedFormatError(format)
+	}
+	fetch, err := Decode(encodedFetch, format)
+	if err != nil {
+		return Message{}, err
+	}
+	if err := validateFetch(fetch); err != nil {
+		return Message{}, err
+	}
+	return fetch, nil
+}
+
+// DecodePatch parses an iPATCH document (application/senml-etch+json or application/senml-etch+cbor) with the given format.
+// Only JSON and CBOR are defined for iPATCH documents (RFC 8790 chapter 6). Records without a value, e.g. with "v":null, remove the matching records when the patch is applied.
+func DecodePatch(encodedPatch []byte, format EncodingFormat) (Message, error) {
+	if format != JSON && format != CBOR {
+		return Message{}, newUnsupportedFormatError(format)
+	}
+	return Decode(encodedPatch, format)
+}
+
+// Select returns the records of the message which are selected by the FETCH document (RFC 8790 chapter 4.2).
+// A record is selected if its name equals the resolved name of a fetch record and, if the fetch record has a time, its time equals the resolved time of the fetch record.
+// The message is expected to be resolved. The selected records keep their order.
+func (message Message) Select(fetch Message) (selectedMessage Message, err error) {
+	if err = validateFetch(fetch); err != nil {
+		return
+	}
+	resolvedFetch, err := resolveETCH(fetch)
+	if err != nil {
+		return
+	}
+	selectedMessage.XMLName = message.XMLName
+	selectedMessage.Stream = message.Stream
+	for _, record := range message.Records {
+		for _, fetchRecord := range resolvedFetch {
+			if matchesETCHRecord(record, fetchRecord) {
+				selectedMessage.Records = append(selectedMessage.Records, record)
+				break
+			}
+		}
+	}
+	return
+}
+
+// Apply applies the iPATCH document to the message (RFC 8790 chapter 4.3) and returns the updated message.
+// Every patch record is matched like a fetch record. The first matching record is replaced by the resolved patch record and further matching records are removed.
+// A patch record without a value removes all matching records, a patch record which matches no record is appended.
+// The message is expected to be resolved, it is not modified.
+func (message Message) Apply(patch Message) (patchedMessage Message, err error) {
+	resolvedPatch, err := resolveETCH(patch)
+	if err != nil {
+		return
+	}
+	patchedMessage.XMLName = message.XMLName
+	patchedMessage.Stream = message.Stream
+	patchedMessage.Records = make([]Record, len(message.Records))
+	copy(patchedMessage.Records, message.Records)
+	for _, patchRecord := range resolvedPatch {
+		var remove = validateRecordHasValue(patchRecord) != nil
+		var replaced = false
+		var records = patchedMessage.Records[:0]
+		for _, record := range patchedMessage.Records {
+			if !matchesETCHRecord(record, patchRecord) {
+				records = append(records, record)
+			} else if !remove && !replaced {
+				records = append(records, patchRecord)
+				replaced = true
+			}
+		}
+		if !remove && !replaced {
+			records = append(records, patchRecord)
+		}
+		patchedMessage.Records = records
+	}
+	return
+}
+
+// validateFetch returns an error if a fetch record contains fields which can't be used to select records
+func validateFetch(fetch Message) error {
+	for index, record := range fetch.Records {
+		for _, field := range []struct {
+			label   string
+			present bool
+		}{
+			{"bu", record.BaseUnit != nil}, {"bv", record.BaseValue != nil}, {"bs", record.BaseSum != nil},
+			{"u", record.Unit != nil}, {"v", record.Value != nil}, {"vs", record.StringValue != nil}, {"vb", record.BoolValue != nil},
+			{"vd", record.DataValue != nil}, {"s", record.Sum != nil}, {"ut", record.UpdateTime != nil},
+			{"bct", record.BaseContentType != nil}, {"ct", record.ContentType != nil},
+		} {
+			if field.present {
+				return newInvalidFetchRecordError(index, field.label)
+			}
+		}
+		if labels := sortedExtensionLabels(record.Extensions); len(labels) > 0 {
+			return newInvalidFetchRecordError(index, labels[0])
+		}
+	}
+	return nil
+}
+
+// resolveETCH resolves the records of a FETCH or iPATCH document, which may have no value, in their original order
+func resolveETCH(document Message) ([]Record, error) {
+	var resolver = NewResolverWithOptions(ResolveOptions{SkipVersionStamping: true})
+	resolver.allowMissingValue = true
+	var resolvedRecords = make([]Record, 0, len(document.Records))
+	for _, record := range document.Records {
+		resolvedRecord, err := resolver.Resolve(record)
+		if err != nil {
+			return nil, err
+		}
+		if record.HasOnlyBaseFields() {
+			continue
+		}
+		resolvedRecords = append(resolvedRecords, resolvedRecord)
+	}
+	return resolvedRecords, nil
+}
+
+// matchesETCHRecord returns true if the record has the name of the resolved fetch or patch record and, if the fetch or patch record has a time, the same time
+func matchesETCHRecord(record Record, etchRecord Record) bool {
+	if record.Name == nil || *record.Name != *etchRecord.Name {
+		return false
+	}
+	return etchRecord.Time == nil || (record.Time != nil && *record.Time == *etchRecord.Time)
+}
@@ -0,0 +1,157 @@
+package senml_test
+
+import (
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+
+	senml "github.com/nkristek/go-senml"
+)
+
+const etchTargetData = `[
+	{"bn":"2001:db8::2/","bt":1.320078429e+09,"n":"temperature","u":"Cel","v":27.2},
+	{"n":"humidity","u":"%RH","v":80},
+	{"n":"switch","vb":true}
+]`
+
+func decodeETCHTarget(t *testing.T) senml.Message {
+	message, err := senml.Decode([]byte(etchTargetData), senml.JSON)
+	if err != nil {
+		t.Fatal("Decoding JSON failed: ", err)
+	}
+	resolvedMessage, err := message.ResolveWithOptions(senml.ResolveOptions{KeepOrder: true})
+	if err != nil {
+		t.Fatal("Resolving the message failed: ", err)
+	}
+	return resolvedMessage
+}
+
+func recordNames(message senml.Message) []string {
+	var names []string
+	for _, record := range message.Records {
+		names = append(names, *record.Name)
+	}
+	return names
+}
+
+func TestSelect(t *testing.T) {
+	var target = decodeETCHTarget(t)
+
+	fetch, err := senml.DecodeFetch([]byte(`[{"bn":"2001:db8::2/","n":"temperature"},{"n":"switch"},{"n":"unknown"}]`), senml.JSON)
+	if err != nil {
+		t.Error("Decoding the FETCH document failed: ", err)
+		return
+	}
+	selectedMessage, err := target.Select(fetch)
+	if err != nil {
+		t.Error("Selecting the records failed: ", err)
+		return
+	}
+	var expected = []string{"2001:db8::2/temperature", "2001:db8::2/switch"}
+	if !reflect.DeepEqual(recordNames(selectedMessage), expected) {
+		t.Errorf("Unexpected records were selected: %v", recordNames(selectedMessage))
+	}
+
+	selectedMessage, err = target.Select(senml.NewFetch("2001:db8::2/humidity"))
+	if err != nil {
+		t.Error("Selecting the records failed: ", err)
+		return
+	}
+	if len(selectedMessage.Records) != 1 || *selectedMessage.Records[0].Value != 80 {
+		t.Error("The record selected by NewFetch is unexpected")
+	}
+
+	var time = 1.0
+	var name = "2001:db8::2/humidity"
+	selectedMessage, err = target.Select(senml.Message{Records: []senml.Record{{Name: &name, Time: &time}}})
+	if err != nil {
+		t.Error("Selecting the records failed: ", err)
+		return
+	}
+	if len(selectedMessage.Records) != 0 {
+		t.Error("A record with a different time should not be selected")
+	}
+}
+
+func TestDecodeFetchInvalid(t *testing.T) {
+	_, err := senml.DecodeFetch([]byte(`[{"n":"temperature"},{"n":"humidity","v":80}]`), senml.JSON)
+	invalidFetchRecordError, ok := err.(*senml.InvalidFetchRecordError)
+	if !ok {
+		t.Error("Decoding a FETCH document with a value should result in an InvalidFetchRecordError, got: ", err)
+		return
+	}
+	if invalidFetchRecordError.Index != 1 || invalidFetchRecordError.Field != "v" {
+		t.Errorf("The error contains an unexpected record or field: %v", invalidFetchRecordError)
+	}
+
+	_, err = senml.DecodeFetch([]byte(`<sensml xmlns="urn:ietf:params:xml:ns:senml"><senml n="temperature"></senml></sensml>`), senml.XML)
+	if _, ok := err.(*senml.UnsupportedFormatError); !ok {
+		t.Error("Decoding a FETCH document in XML should result in an UnsupportedFormatError, got: ", err)
+	}
+	_, err = senml.DecodePatch([]byte(`<sensml xmlns="urn:ietf:params:xml:ns:senml"><senml n="temperature"></senml></sensml>`), senml.XML)
+	if _, ok := err.(*senml.UnsupportedFormatError); !ok {
+		t.Error("Decoding an iPATCH document in XML should result in an UnsupportedFormatError, got: ", err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	var target = decodeETCHTarget(t)
+
+	patch, err := senml.DecodePatch([]byte(`[{"bn":"2001:db8::2/","n":"temperature","u":"Cel","v":28},{"n":"switch","vb":null},{"n":"co2","u":"ppm","v":400}]`), senml.JSON)
+	if err != nil {
+		t.Error("Decoding the iPATCH document failed: ", err)
+		return
+	}
+	patchedMessage, err := target.Apply(patch)
+	if err != nil {
+		t.Error("Applying the patch failed: ", err)
+		return
+	}
+	var expected = []string{"2001:db8::2/temperature", "2001:db8::2/humidity", "2001:db8::2/co2"}
+	if !reflect.DeepEqual(recordNames(patchedMessage), expected) {
+		t.Errorf("The patch resulted in unexpected records: %v", recordNames(patchedMessage))
+		return
+	}
+	if *patchedMessage.Records[0].Value != 28 || patchedMessage.Records[0].Time != nil {
+		t.Error("The matching record was not replaced by the patch record")
+	}
+	if *patchedMessage.Records[2].Unit != "ppm" {
+		t.Error("The new record was not appended")
+	}
+	if len(target.Records) != 3 || *target.Records[0].Value != 27.2 {
+		t.Error("Applying a patch should not modify the message")
+	}
+}
+
+func TestApplyCBORNull(t *testing.T) {
+	var target = decodeETCHTarget(t)
+
+	// [{0: "2001:db8::2/humidity", 2: null}]
+	data, _ := hex.DecodeString("81a20074323030313a6462383a3a322f68756d696469747902f6")
+	patch, err := senml.DecodePatch(data, senml.CBOR)
+	if err != nil {
+		t.Error("Decoding the iPATCH document failed: ", err)
+		return
+	}
+	patchedMessage, err := target.Apply(patch)
+	if err != nil {
+		t.Error("Applying the patch failed: ", err)
+		return
+	}
+	var expected = []string{"2001:db8::2/temperature", "2001:db8::2/switch"}
+	if !reflect.DeepEqual(recordNames(patchedMessage), expected) {
+		t.Errorf("The record with a null value was not removed: %v", recordNames(patchedMessage))
+	}
+}
+
+func TestInvalidFetchRecordError(t *testing.T) {
+	err := &senml.InvalidFetchRecordError{
+		Index: 3,
+		Field: "v",
+	}
+	message := err.Error()
+	if !strings.Contains(message, "3") || !strings.Contains(message, "\"v\"") {
+		t.Error("The error message does not contain the index and field.")
+	}
+}
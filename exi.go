@@ -0,0 +1,763 @@
+package senml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// the schemaId of the SenML schema as defined in RFC 8428 chapter 8
+const exiSchemaID = "a"
+
+// the namespace of the SenML XML elements
+const xmlNamespace = "urn:ietf:params:xml:ns:senml"
+
+// the optional EXI cookie which may precede the EXI header
+const exiCookie = "$EXI"
+
+// the exponent which marks the special values INF, -INF and NaN of the EXI Float datatype
+const exiFloatSpecialExponent = -(1 << 14)
+
+// the event codes of the document content: the global elements senml and sensml in lexicographical order, followed by SE(*)
+const (
+	exiDocumentSenml = iota
+	exiDocumentSensml
+	exiDocumentProductions = 3
+)
+
+// exiDatatype declares the EXI datatype representation of an attribute value
+type exiDatatype int
+
+const (
+	exiString exiDatatype = iota
+	exiFloat
+	exiInteger
+	exiBoolean
+)
+
+// exiAttribute is an attribute of the senml element in the SenML schema
+type exiAttribute struct {
+	name     string
+	datatype exiDatatype
+}
+
+// exiAttributes contains the attributes of the senml element in the order of their event codes, which is the lexicographical order of their names.
+// The datatypes are those of the schema in RFC 8428 chapter 8, which declares vd as xs:string, so the base64url text is encoded instead of the binary data.
+var exiAttributes = []exiAttribute{
+	{"bn", exiString},
+	{"bs", exiFloat},
+	{"bt", exiFloat},
+	{"bu", exiString},
+	{"bv", exiFloat},
+	{"bver", exiInteger},
+	{"n", exiString},
+	{"s", exiFloat},
+	{"t", exiFloat},
+	{"u", exiString},
+	{"ut", exiFloat},
+	{"v", exiFloat},
+	{"vb", exiBoolean},
+	{"vd", exiString},
+	{"vs", exiString},
+}
+
+// InvalidEXIError is an error which is returned when an EXI encoded message is malformed or was not encoded using the SenML schema in strict mode.
+type InvalidEXIError struct {
+	// The reason why the message is invalid
+	Reason string
+}
+
+func (err *InvalidEXIError) Error() string {
+	return fmt.Sprintf("Invalid EXI: %v", err.Reason)
+}
+
+func newInvalidEXIError(reason string) *InvalidEXIError {
+	return &InvalidEXIError{
+		Reason: reason,
+	}
+}
+
+// UnsupportedEXIFieldError is an error which is returned when a record is encoded as EXI which contains a field the SenML schema of RFC 8428 chapter 8 doesn't declare, e.g. an extension.
+// Dropping the field would silently change the message, e.g. a label which must be understood would be lost.
+type UnsupportedEXIFieldError struct {
+	// The index of the record in the message
+	Index int
+
+	// The label of the field
+	Label string
+}
+
+func (err *UnsupportedEXIFieldError) Error() string {
+	return fmt.Sprintf("The field %q of record %v can't be encoded as EXI, since the SenML schema doesn't contain it", err.Label, err.Index)
+}
+
+func newUnsupportedEXIFieldError(index int, label string) *UnsupportedEXIFieldError {
+	return &UnsupportedEXIFieldError{
+		Index: index,
+		Label: label,
+	}
+}
+
+// EmptyEXIMessageError is an error which is returned when a message without records is encoded as EXI, since the SenML schema requires at least one senml element.
+type EmptyEXIMessageError struct{}
+
+func (err *EmptyEXIMessageError) Error() string {
+	return "A message without records can't be encoded as EXI, since the SenML schema requires at least one record"
+}
+
+func encodeEXI(message Message) ([]byte, error) {
+	if len(message.Records) == 0 {
+		return nil, &EmptyEXIMessageError{}
+	}
+	var writer = exiWriter{stringTable: newEXIStringTable()}
+	writer.writeHeader()
+
+	// SE(sensml), the document content of a schema-informed grammar has the productions SE(senml), SE(sensml) and SE(*) even in strict mode
+	writer.writeBits(exiDocumentSensml, exiCodeWidth(exiDocumentProductions))
+	for recordIndex, record := range message.Records {
+		if label, ok := unsupportedEXILabel(record); ok {
+			return nil, newUnsupportedEXIFieldError(recordIndex, label)
+		}
+		var values = exiAttributeValues(record)
+
+		// SE(senml), the first one is the only production of the content of sensml and needs no bits
+		if recordIndex > 0 {
+			writer.writeBits(0, 1)
+		}
+		var state = 0
+		for index, value := range values {
+			if value == nil {
+				continue
+			}
+			writer.writeBits(uint64(index-state), exiCodeWidth(len(exiAttributes)-state+1))
+			writer.writeValue(exiAttributes[index], value)
+			state = index + 1
+		}
+		// EE(senml)
+		writer.writeBits(uint64(len(exiAttributes)-state), exiCodeWidth(len(exiAttributes)-state+1))
+	}
+	// EE(sensml)
+	writer.writeBits(1, 1)
+	return writer.bytes(), nil
+}
+
+func decodeEXI(encodedMessage []byte) (message Message, err error) {
+	if bytes.HasPrefix(encodedMessage, []byte(exiCookie)) {
+		encodedMessage = encodedMessage[len(exiCookie):]
+	}
+	var reader = exiReader{data: encodedMessage, stringTable: newEXIStringTable()}
+	if err = reader.readHeader(); err != nil {
+		return
+	}
+
+	root, err := reader.readBits(exiCodeWidth(exiDocumentProductions))
+	if err != nil {
+		return
+	}
+	if root > exiDocumentSensml {
+		err = newInvalidEXIError("the root element must be senml or sensml")
+		return
+	}
+	if root == exiDocumentSenml {
+		// the document consists of a single senml element
+		var record Record
+		record, err = reader.readRecord()
+		if err != nil {
+			return
+		}
+		message.Records = append(message.Records, record)
+		return
+	}
+
+	message.XMLName = xml.Name{Space: xmlNamespace, Local: "sensml"}
+	for {
+		if len(message.Records) > 0 {
+			// SE(senml) or EE(sensml), the first senml element has no alternative and its event code needs no bits
+			var event uint64
+			event, err = reader.readBits(1)
+			if err != nil {
+				return
+			}
+			if event == 1 {
+				return
+			}
+		}
+		var record Record
+		record, err = reader.readRecord()
+		if err != nil {
+			return
+		}
+		message.Records = append(message.Records, record)
+	}
+}
+
+// unsupportedEXILabel returns the label of a field of the record which the SenML schema doesn't contain and true, or false if all fields can be encoded
+func unsupportedEXILabel(record Record) (string, bool) {
+	if record.BaseContentType != nil {
+		return "bct", true
+	}
+	if record.ContentType != nil {
+		return "ct", true
+	}
+	if len(record.Extensions) == 0 {
+		return "", false
+	}
+	var labels = make([]string, 0, len(record.Extensions))
+	for label := range record.Extensions {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels[0], true
+}
+
+// exiAttributeValues returns the values of the record in the order of exiAttributes. Values of fields which are not set are nil.
+func exiAttributeValues(record Record) []interface{} {
+	var values = make([]interface{}, len(exiAttributes))
+	if record.BaseName != nil {
+		values[0] = *record.BaseName
+	}
+	if record.BaseSum != nil {
+		values[1] = *record.BaseSum
+	}
+	if record.BaseTime != nil {
+		values[2] = *record.BaseTime
+	}
+	if record.BaseUnit != nil {
+		values[3] = *record.BaseUnit
+	}
+	if record.BaseValue != nil {
+		values[4] = *record.BaseValue
+	}
+	if record.BaseVersion != nil {
+		values[5] = int64(*record.BaseVersion)
+	}
+	if record.Name != nil {
+		values[6] = *record.Name
+	}
+	if record.Sum != nil {
+		values[7] = *record.Sum
+	}
+	if record.Time != nil {
+		values[8] = *record.Time
+	}
+	if record.Unit != nil {
+		values[9] = *record.Unit
+	}
+	if record.UpdateTime != nil {
+		values[10] = *record.UpdateTime
+	}
+	if record.Value != nil {
+		values[11] = *record.Value
+	}
+	if record.BoolValue != nil {
+		values[12] = *record.BoolValue
+	}
+	if record.DataValue != nil {
+		values[13] = *record.DataValue
+	}
+	if record.StringValue != nil {
+		values[14] = *record.StringValue
+	}
+	return values
+}
+
+// setEXIAttributeValue sets the field of the record which corresponds to the attribute at the given index of exiAttributes
+func setEXIAttributeValue(record *Record, index int, value interface{}) {
+	switch index {
+	case 0:
+		var stringValue = value.(string)
+		record.BaseName = &stringValue
+	case 1:
+		var floatValue = value.(float64)
+		record.BaseSum = &floatValue
+	case 2:
+		var floatValue = value.(float64)
+		record.BaseTime = &floatValue
+	case 3:
+		var stringValue = value.(string)
+		record.BaseUnit = &stringValue
+	case 4:
+		var floatValue = value.(float64)
+		record.BaseValue = &floatValue
+	case 5:
+		var intValue = int(value.(int64))
+		record.BaseVersion = &intValue
+	case 6:
+		var stringValue = value.(string)
+		record.Name = &stringValue
+	case 7:
+		var floatValue = value.(float64)
+		record.Sum = &floatValue
+	case 8:
+		var floatValue = value.(float64)
+		record.Time = &floatValue
+	case 9:
+		var stringValue = value.(string)
+		record.Unit = &stringValue
+	case 10:
+		var floatValue = value.(float64)
+		record.UpdateTime = &floatValue
+	case 11:
+		var floatValue = value.(float64)
+		record.Value = &floatValue
+	case 12:
+		var boolValue = value.(bool)
+		record.BoolValue = &boolValue
+	case 13:
+		var stringValue = value.(string)
+		record.DataValue = &stringValue
+	case 14:
+		var stringValue = value.(string)
+		record.StringValue = &stringValue
+	}
+}
+
+// exiCodeWidth returns the number of bits needed to encode an event code of a grammar state with the given number of productions
+func exiCodeWidth(productions int) int {
+	var width = 0
+	for (1 << uint(width)) < productions {
+		width++
+	}
+	return width
+}
+
+// exiStringTable contains the global and local value partitions of the EXI string table
+type exiStringTable struct {
+	global      []string
+	globalIndex map[string]int
+	local       map[string][]string
+	localIndex  map[string]map[string]int
+}
+
+func newEXIStringTable() *exiStringTable {
+	return &exiStringTable{
+		globalIndex: map[string]int{},
+		local:       map[string][]string{},
+		localIndex:  map[string]map[string]int{},
+	}
+}
+
+func (table *exiStringTable) add(qname string, value string) {
+	if len(value) == 0 {
+		return
+	}
+	table.globalIndex[value] = len(table.global)
+	table.global = append(table.global, value)
+	if table.localIndex[qname] == nil {
+		table.localIndex[qname] = map[string]int{}
+	}
+	table.localIndex[qname][value] = len(table.local[qname])
+	table.local[qname] = append(table.local[qname], value)
+}
+
+// exiWriter writes an EXI stream in bit-packed alignment
+type exiWriter struct {
+	buffer      bytes.Buffer
+	current     byte
+	count       uint
+	stringTable *exiStringTable
+}
+
+// writeHeader writes the EXI header including the EXI options, which declare strict mode and the schemaId of the SenML schema
+func (writer *exiWriter) writeHeader() {
+	// distinguishing bits, presence bit for EXI options, final version and version 1
+	writer.writeBits(0x2, 2)
+	writer.writeBits(1, 1)
+	writer.writeBits(0, 1)
+	writer.writeBits(0, 4)
+
+	// the EXI options document: <header><common><schemaId>a</schemaId></common><strict/></header>
+	var optionsTable = newEXIStringTable()
+	// SE(header) out of SE(header) and SE(*), SE(common), SE(schemaId) and CH, which precedes AT(xsi:nil) since the schemaId is nillable
+	writer.writeBits(0, 1)
+	writer.writeBits(1, 2)
+	writer.writeBits(2, 2)
+	writer.writeBits(0, 1)
+	writer.writeString(optionsTable, "schemaId", exiSchemaID)
+	// SE(strict), all remaining EE events have only a single production and need no bits
+	writer.writeBits(0, 1)
+}
+
+func (writer *exiWriter) writeBits(value uint64, width int) {
+	for i := width - 1; i >= 0; i-- {
+		writer.current = writer.current<<1 | byte(value>>uint(i)&1)
+		writer.count++
+		if writer.count == 8 {
+			writer.buffer.WriteByte(writer.current)
+			writer.current = 0
+			writer.count = 0
+		}
+	}
+}
+
+func (writer *exiWriter) writeUnsignedInteger(value uint64) {
+	for {
+		var octet = value & 0x7f
+		value >>= 7
+		if value != 0 {
+			octet |= 0x80
+		}
+		writer.writeBits(octet, 8)
+		if value == 0 {
+			return
+		}
+	}
+}
+
+func (writer *exiWriter) writeInteger(value int64) {
+	if value < 0 {
+		writer.writeBits(1, 1)
+		writer.writeUnsignedInteger(uint64(-(value + 1)))
+	} else {
+		writer.writeBits(0, 1)
+		writer.writeUnsignedInteger(uint64(value))
+	}
+}
+
+func (writer *exiWriter) writeFloat(value float64) {
+	var mantissa, exponent = exiFloatParts(value)
+	writer.writeInteger(mantissa)
+	writer.writeInteger(exponent)
+}
+
+func (writer *exiWriter) writeString(table *exiStringTable, qname string, value string) {
+	if index, ok := table.localIndex[qname][value]; ok {
+		writer.writeUnsignedInteger(0)
+		writer.writeBits(uint64(index), exiCodeWidth(len(table.local[qname])))
+		return
+	}
+	if index, ok := table.globalIndex[value]; ok {
+		writer.writeUnsignedInteger(1)
+		writer.writeBits(uint64(index), exiCodeWidth(len(table.global)))
+		return
+	}
+	writer.writeUnsignedInteger(uint64(utf8.RuneCountInString(value)) + 2)
+	for _, character := range value {
+		writer.writeUnsignedInteger(uint64(character))
+	}
+	table.add(qname, value)
+}
+
+func (writer *exiWriter) writeValue(attribute exiAttribute, value interface{}) {
+	switch attribute.datatype {
+	case exiString:
+		writer.writeString(writer.stringTable, attribute.name, value.(string))
+	case exiFloat:
+		writer.writeFloat(value.(float64))
+	case exiInteger:
+		writer.writeInteger(value.(int64))
+	case exiBoolean:
+		if value.(bool) {
+			writer.writeBits(1, 1)
+		} else {
+			writer.writeBits(0, 1)
+		}
+	}
+}
+
+// bytes returns the written stream padded with zero bits to the next byte boundary
+func (writer *exiWriter) bytes() []byte {
+	if writer.count > 0 {
+		writer.buffer.WriteByte(writer.current << (8 - writer.count))
+		writer.current = 0
+		writer.count = 0
+	}
+	return writer.buffer.Bytes()
+}
+
+// exiReader reads an EXI stream in bit-packed alignment
+type exiReader struct {
+	data        []byte
+	position    int
+	stringTable *exiStringTable
+}
+
+// readHeader reads the EXI header. If EXI options are present, they have to declare strict mode and the schemaId of the SenML schema.
+func (reader *exiReader) readHeader() error {
+	distinguishingBits, err := reader.readBits(2)
+	if err != nil {
+		return err
+	}
+	if distinguishingBits != 0x2 {
+		return newInvalidEXIError("the distinguishing bits are missing")
+	}
+	optionsPresent, err := reader.readBits(1)
+	if err != nil {
+		return err
+	}
+	preview, err := reader.readBits(1)
+	if err != nil {
+		return err
+	}
+	version, err := reader.readBits(4)
+	if err != nil {
+		return err
+	}
+	if preview != 0 || version != 0 {
+		return newInvalidEXIError("only the final version 1 of the EXI format is supported")
+	}
+	if optionsPresent == 0 {
+		return nil
+	}
+
+	var optionsTable = newEXIStringTable()
+	var schemaID *string
+	var strict bool
+	// SE(header) or SE(*)
+	event, err := reader.readBits(1)
+	if err != nil {
+		return err
+	}
+	if event != 0 {
+		return newInvalidEXIError("the EXI options must start with the header element")
+	}
+	event, err = reader.readBits(2)
+	if err != nil {
+		return err
+	}
+	switch event {
+	case 0:
+		return newInvalidEXIError("the EXI options lesscommon are not supported")
+	case 1:
+		// SE(common)
+		event, err = reader.readBits(2)
+		if err != nil {
+			return err
+		}
+		if event != 2 {
+			return newInvalidEXIError("only the EXI option schemaId is supported inside common")
+		}
+		event, err = reader.readBits(1)
+		if err != nil {
+			return err
+		}
+		if event != 0 {
+			return newInvalidEXIError("the schemaId must not be nil")
+		}
+		value, err := reader.readString(optionsTable, "schemaId")
+		if err != nil {
+			return err
+		}
+		schemaID = &value
+		// SE(strict) or EE(header)
+		event, err = reader.readBits(1)
+		if err != nil {
+			return err
+		}
+		strict = event == 0
+	case 2:
+		strict = true
+	}
+	if schemaID == nil || *schemaID != exiSchemaID {
+		return newInvalidEXIError(fmt.Sprintf("the schemaId must be %q", exiSchemaID))
+	}
+	if !strict {
+		return newInvalidEXIError("only the strict mode is supported")
+	}
+	return nil
+}
+
+func (reader *exiReader) readRecord() (record Record, err error) {
+	record.XMLName = xml.Name{Space: xmlNamespace, Local: "senml"}
+	var state = 0
+	for {
+		var code uint64
+		code, err = reader.readBits(exiCodeWidth(len(exiAttributes) - state + 1))
+		if err != nil {
+			return
+		}
+		var index = state + int(code)
+		if index == len(exiAttributes) {
+			// EE(senml)
+			return
+		}
+		if index > len(exiAttributes) {
+			err = newInvalidEXIError("invalid event code")
+			return
+		}
+		var value interface{}
+		value, err = reader.readValue(exiAttributes[index])
+		if err != nil {
+			return
+		}
+		setEXIAttributeValue(&record, index, value)
+		state = index + 1
+	}
+}
+
+func (reader *exiReader) readBits(width int) (uint64, error) {
+	if reader.position+width > len(reader.data)*8 {
+		return 0, newInvalidEXIError("unexpected end of data")
+	}
+	var value uint64
+	for i := 0; i < width; i++ {
+		var bit = reader.data[reader.position/8] >> uint(7-reader.position%8) & 1
+		value = value<<1 | uint64(bit)
+		reader.position++
+	}
+	return value, nil
+}
+
+func (reader *exiReader) remainingBits() int {
+	return len(reader.data)*8 - reader.position
+}
+
+func (reader *exiReader) readUnsignedInteger() (uint64, error) {
+	var value uint64
+	for shift := uint(0); ; shift += 7 {
+		if shift > 63 {
+			return 0, newInvalidEXIError("unsigned integer overflow")
+		}
+		octet, err := reader.readBits(8)
+		if err != nil {
+			return 0, err
+		}
+		value |= (octet & 0x7f) << shift
+		if octet&0x80 == 0 {
+			return value, nil
+		}
+	}
+}
+
+func (reader *exiReader) readInteger() (int64, error) {
+	negative, err := reader.readBits(1)
+	if err != nil {
+		return 0, err
+	}
+	magnitude, err := reader.readUnsignedInteger()
+	if err != nil {
+		return 0, err
+	}
+	if magnitude > math.MaxInt64 {
+		return 0, newInvalidEXIError("integer overflow")
+	}
+	if negative == 1 {
+		return -int64(magnitude) - 1, nil
+	}
+	return int64(magnitude), nil
+}
+
+func (reader *exiReader) readFloat() (float64, error) {
+	mantissa, err := reader.readInteger()
+	if err != nil {
+		return 0, err
+	}
+	exponent, err := reader.readInteger()
+	if err != nil {
+		return 0, err
+	}
+	if exponent == exiFloatSpecialExponent {
+		switch mantissa {
+		case 1:
+			return math.Inf(1), nil
+		case -1:
+			return math.Inf(-1), nil
+		default:
+			return math.NaN(), nil
+		}
+	}
+	if exponent < exiFloatSpecialExponent || exponent > -exiFloatSpecialExponent-1 {
+		return 0, newInvalidEXIError("float exponent out of range")
+	}
+	return strconv.ParseFloat(fmt.Sprintf("%de%d", mantissa, exponent), 64)
+}
+
+func (reader *exiReader) readString(table *exiStringTable, qname string) (string, error) {
+	length, err := reader.readUnsignedInteger()
+	if err != nil {
+		return "", err
+	}
+	switch length {
+	case 0:
+		index, err := reader.readBits(exiCodeWidth(len(table.local[qname])))
+		if err != nil {
+			return "", err
+		}
+		if index >= uint64(len(table.local[qname])) {
+			return "", newInvalidEXIError("invalid local value string table index")
+		}
+		return table.local[qname][index], nil
+	case 1:
+		index, err := reader.readBits(exiCodeWidth(len(table.global)))
+		if err != nil {
+			return "", err
+		}
+		if index >= uint64(len(table.global)) {
+			return "", newInvalidEXIError("invalid global value string table index")
+		}
+		return table.global[index], nil
+	}
+
+	length -= 2
+	if length > uint64(reader.remainingBits()/8) {
+		return "", newInvalidEXIError("unexpected end of data")
+	}
+	var builder strings.Builder
+	for i := uint64(0); i < length; i++ {
+		character, err := reader.readUnsignedInteger()
+		if err != nil {
+			return "", err
+		}
+		if character > utf8.MaxRune || !utf8.ValidRune(rune(character)) {
+			return "", newInvalidEXIError("invalid character")
+		}
+		builder.WriteRune(rune(character))
+	}
+	var value = builder.String()
+	table.add(qname, value)
+	return value, nil
+}
+
+func (reader *exiReader) readValue(attribute exiAttribute) (interface{}, error) {
+	switch attribute.datatype {
+	case exiString:
+		return reader.readString(reader.stringTable, attribute.name)
+	case exiFloat:
+		return reader.readFloat()
+	case exiInteger:
+		value, err := reader.readInteger()
+		if err != nil {
+			return nil, err
+		}
+		if value < math.MinInt32 || value > math.MaxInt32 {
+			return nil, newInvalidEXIError("integer out of range")
+		}
+		return value, nil
+	default:
+		value, err := reader.readBits(1)
+		if err != nil {
+			return nil, err
+		}
+		return value == 1, nil
+	}
+}
+
+// exiFloatParts returns the decimal mantissa and exponent of the value as used by the EXI Float datatype
+func exiFloatParts(value float64) (mantissa int64, exponent int64) {
+	switch {
+	case math.IsInf(value, 1):
+		return 1, exiFloatSpecialExponent
+	case math.IsInf(value, -1):
+		return -1, exiFloatSpecialExponent
+	case math.IsNaN(value):
+		return 0, exiFloatSpecialExponent
+	}
+
+	// the shortest representation which results in the same value, e.g. "-1.201e+02"
+	var formatted = strconv.FormatFloat(value, 'e', -1, 64)
+	var separator = strings.IndexByte(formatted, 'e')
+	var digits = strings.Replace(formatted[:separator], ".", "", 1)
+	var negative = strings.HasPrefix(digits, "-")
+	digits = strings.TrimPrefix(digits, "-")
+	decimalExponent, _ := strconv.ParseInt(formatted[separator+1:], 10, 64)
+	mantissa, _ = strconv.ParseInt(digits, 10, 64)
+	if negative {
+		mantissa = -mantissa
+	}
+	return mantissa, decimalExponent - int64(len(digits)-1)
+}
@@ -0,0 +1,306 @@
+package senml_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	senml "github.com/nkristek/go-senml"
+)
+
+func TestEncodeEXI(t *testing.T) {
+	message, err := senml.Decode([]byte(xmlData), senml.XML)
+	if err != nil {
+		t.Error("Decoding XML failed: ", err)
+		return
+	}
+
+	encodedMessage, err := message.Encode(senml.EXI)
+	if err != nil {
+		t.Error("Encoding message to EXI failed: ", err)
+		return
+	}
+
+	// EXI header with the options for strict mode and schemaId "a", followed by SE(sensml)
+	var expectedHeader, _ = hex.DecodeString("a0300d84")
+	if !bytes.HasPrefix(encodedMessage, expectedHeader) {
+		t.Errorf("Encoding to EXI resulted in an unexpected header: %x", encodedMessage)
+	}
+
+	encodedXML, _ := message.Encode(senml.XML)
+	if len(encodedMessage) >= len(encodedXML) {
+		t.Error("Encoding to EXI did not result in a smaller message than XML")
+	}
+}
+
+func TestDecodeEXIRoundTripWithXML(t *testing.T) {
+	message, err := senml.Decode([]byte(xmlData), senml.XML)
+	if err != nil {
+		t.Error("Decoding XML failed: ", err)
+		return
+	}
+
+	encodedMessage, err := message.Encode(senml.EXI)
+	if err != nil {
+		t.Error("Encoding message to EXI failed: ", err)
+		return
+	}
+
+	decodedMessage, err := senml.Decode(encodedMessage, senml.EXI)
+	if err != nil {
+		t.Error("Decoding EXI failed: ", err)
+		return
+	}
+
+	if !reflect.DeepEqual(message, decodedMessage) {
+		t.Error("The message changed while encoding and decoding EXI")
+		return
+	}
+
+	encodedXML, _ := message.Encode(senml.XML)
+	decodedXML, _ := decodedMessage.Encode(senml.XML)
+	if !bytes.Equal(encodedXML, decodedXML) {
+		t.Errorf("The XML representation changed while encoding and decoding EXI. expected: %s, got: %s", encodedXML, decodedXML)
+	}
+}
+
+// a pack with the voltage and current records of the RFC 8428 examples
+const exiExampleXML = `<sensml xmlns="urn:ietf:params:xml:ns:senml"><senml bn="urn:dev:ow:10e2073a01080063:" n="voltage" u="V" v="120.1"></senml><senml n="current" u="A" v="1.2"></senml></sensml>`
+
+// the EXI representation of exiExampleXML, derived bit by bit from the grammar of the schema of RFC 8428 chapter 8 in strict mode with schemaId "a":
+// the header with the options, SE(sensml) out of SE(senml), SE(sensml) and SE(*), the first SE(senml) which needs no bits since sensml requires at least one senml element,
+// AT(bn), AT(n), AT(u) and AT(v) with string literals and floats (1201E-1), EE(senml), SE(senml), AT(n), AT(u), AT(v) (12E-1), EE(senml) and EE(sensml)
+const exiExampleHex = "a0300d8480f3ab9371d3232bb1d37bb9d18983299181b99b09818981c18181b199d284bb37b63a30b3b2901ab15884c03304b1bab93932b73a101a09064038"
+
+func TestEXIExample(t *testing.T) {
+	message, err := senml.Decode([]byte(exiExampleXML), senml.XML)
+	if err != nil {
+		t.Error("Decoding XML failed: ", err)
+		return
+	}
+
+	encodedMessage, err := message.Encode(senml.EXI)
+	if err != nil {
+		t.Error("Encoding message to EXI failed: ", err)
+		return
+	}
+	if hex.EncodeToString(encodedMessage) != exiExampleHex {
+		t.Errorf("Encoding the example to EXI resulted in unexpected data. expected: %s, got: %x", exiExampleHex, encodedMessage)
+	}
+
+	data, _ := hex.DecodeString(exiExampleHex)
+	decodedMessage, err := senml.Decode(data, senml.EXI)
+	if err != nil {
+		t.Error("Decoding the EXI example failed: ", err)
+		return
+	}
+	if !reflect.DeepEqual(message, decodedMessage) {
+		t.Error("Decoding the EXI example resulted in an unexpected message")
+	}
+}
+
+func TestEncodeEXIDataValueAsString(t *testing.T) {
+	var name = "nfc-reader"
+	var dataValue = "aGkgCg"
+	var message = senml.Message{
+		Records: []senml.Record{
+			{Name: &name, DataValue: &dataValue},
+		},
+	}
+	encodedMessage, err := message.Encode(senml.EXI)
+	if err != nil {
+		t.Error("Encoding message to EXI failed: ", err)
+		return
+	}
+	// vd is declared as xs:string, so the base64url text is encoded as a string literal: AT(vd), the length + 2 and the characters
+	const expected = "a0300d84b0637333196b932b0b232b930430a3b5b3a1b3e0"
+	if hex.EncodeToString(encodedMessage) != expected {
+		t.Errorf("The data value was not encoded as string. expected: %s, got: %x", expected, encodedMessage)
+	}
+	decodedMessage, err := senml.Decode(encodedMessage, senml.EXI)
+	if err != nil || decodedMessage.Records[0].DataValue == nil || *decodedMessage.Records[0].DataValue != dataValue {
+		t.Error("The data value changed while encoding and decoding EXI: ", err)
+	}
+}
+
+func TestEncodeEXIEmptyMessage(t *testing.T) {
+	_, err := senml.Message{}.Encode(senml.EXI)
+	if _, ok := err.(*senml.EmptyEXIMessageError); !ok {
+		t.Error("Encoding a message without records as EXI should result in an EmptyEXIMessageError, got: ", err)
+	}
+	if (&senml.EmptyEXIMessageError{}).Error() == "" {
+		t.Error("The error message is empty.")
+	}
+}
+
+func TestDecodeEXIAllFields(t *testing.T) {
+	const xmlMessage = `<sensml xmlns="urn:ietf:params:xml:ns:senml">
+		<senml bn="urn:dev:ow:10e2073a01080063:" bt="1.320067464e+09" bu="%RH" bv="-1.5" bs="100000" bver="5"
+		n="humidity" u="%EL" v="20.25" s="0.1" t="-60" ut="3600"></senml>
+		<senml n="label" vs="Machine Room"></senml>
+		<senml n="label" vs="urn:dev:ow:10e2073a01080063:"></senml>
+		<senml n="open" vb="false"></senml>
+		<senml n="closed" vb="true"></senml>
+		<senml n="nfc-reader" vd="aGkgCg"></senml>
+		<senml n="ünïcödé" vs=""></senml>
+	</sensml>`
+	message, err := senml.Decode([]byte(xmlMessage), senml.XML)
+	if err != nil {
+		t.Error("Decoding XML failed: ", err)
+		return
+	}
+
+	encodedMessage, err := message.Encode(senml.EXI)
+	if err != nil {
+		t.Error("Encoding message to EXI failed: ", err)
+		return
+	}
+
+	decodedMessage, err := senml.Decode(encodedMessage, senml.EXI)
+	if err != nil {
+		t.Error("Decoding EXI failed: ", err)
+		return
+	}
+
+	if !reflect.DeepEqual(message, decodedMessage) {
+		t.Error("The message changed while encoding and decoding EXI")
+	}
+}
+
+func TestDecodeEXISpecialFloatValues(t *testing.T) {
+	var name = "test"
+	for _, value := range []float64{math.Inf(1), math.Inf(-1), math.NaN(), 0, -0.001, 1e300, 5e-324} {
+		var value = value
+		message := senml.Message{
+			Records: []senml.Record{
+				{
+					Name:  &name,
+					Value: &value,
+				},
+			},
+		}
+
+		encodedMessage, err := message.Encode(senml.EXI)
+		if err != nil {
+			t.Error("Encoding message to EXI failed: ", err)
+			return
+		}
+
+		decodedMessage, err := senml.Decode(encodedMessage, senml.EXI)
+		if err != nil {
+			t.Error("Decoding EXI failed: ", err)
+			return
+		}
+
+		var decodedValue = *decodedMessage.Records[0].Value
+		if decodedValue != value && !(math.IsNaN(value) && math.IsNaN(decodedValue)) {
+			t.Errorf("The value %v was decoded as %v", value, decodedValue)
+		}
+	}
+}
+
+func TestDecodeEXIWithoutOptionsAndWithCookie(t *testing.T) {
+	var name = "test"
+	var value float64 = 1
+	message := senml.Message{
+		Records: []senml.Record{
+			{
+				Name:  &name,
+				Value: &value,
+			},
+		},
+	}
+	encodedMessage, err := message.Encode(senml.EXI)
+	if err != nil {
+		t.Error("Encoding message to EXI failed: ", err)
+		return
+	}
+
+	decodedMessage, err := senml.Decode(append([]byte("$EXI"), encodedMessage...), senml.EXI)
+	if err != nil {
+		t.Error("Decoding EXI with a cookie failed: ", err)
+		return
+	}
+	if len(decodedMessage.Records) != 1 || *decodedMessage.Records[0].Name != name {
+		t.Error("Decoding EXI with a cookie resulted in an unexpected message")
+	}
+
+	// header without options, SE(sensml), AT(n) with "a", EE(senml), EE(sensml)
+	encodedMessage, _ = hex.DecodeString("80580d8620")
+	decodedMessage, err = senml.Decode(encodedMessage, senml.EXI)
+	if err != nil {
+		t.Error("Decoding EXI without options failed: ", err)
+		return
+	}
+	if len(decodedMessage.Records) != 1 || decodedMessage.Records[0].Name == nil || *decodedMessage.Records[0].Name != "a" {
+		t.Error("Decoding EXI without options resulted in an unexpected message")
+	}
+}
+
+func TestDecodeEXIInvalid(t *testing.T) {
+	var invalidMessages = map[string]string{
+		"empty":                   "",
+		"no distinguishing bits":  "00",
+		"unsupported version":     "a1",
+		"lesscommon options":      "a000",
+		"different schemaId":      "a0300d88",
+		"truncated":               "a0300d85",
+		"invalid attribute event": "80400d87c0",
+		"wildcard root element":   "8080",
+	}
+	for name, invalidMessage := range invalidMessages {
+		data, _ := hex.DecodeString(invalidMessage)
+		_, err := senml.Decode(data, senml.EXI)
+		if err == nil {
+			t.Errorf("Decoding invalid EXI (%v) should result in an error", name)
+			continue
+		}
+		if _, ok := err.(*senml.InvalidEXIError); !ok {
+			t.Errorf("Decoding invalid EXI (%v) resulted in an unexpected error type: %T", name, err)
+		}
+	}
+}
+
+func TestEncodeEXIWithExtensions(t *testing.T) {
+	const data = `[{"n":"temperature","v":23.1},{"n":"door","vb":true,"vendor":"acme","foo_":"bar"}]`
+	message, err := senml.Decode([]byte(data), senml.JSON)
+	if err != nil {
+		t.Error("Decoding JSON failed: ", err)
+		return
+	}
+	for _, format := range []senml.EncodingFormat{senml.EXI, senml.SenSMLEXI} {
+		_, err = message.Encode(format)
+		unsupportedFieldError, ok := err.(*senml.UnsupportedEXIFieldError)
+		if !ok {
+			t.Error("Encoding a record with extensions as EXI should result in an UnsupportedEXIFieldError, got: ", err)
+			continue
+		}
+		if unsupportedFieldError.Index != 1 || unsupportedFieldError.Label != "foo_" {
+			t.Errorf("The error contains an unexpected record or label: %v", unsupportedFieldError)
+		}
+	}
+}
+
+func TestUnsupportedEXIFieldError(t *testing.T) {
+	err := &senml.UnsupportedEXIFieldError{
+		Index: 3,
+		Label: "foo_",
+	}
+	message := err.Error()
+	if !strings.Contains(message, "foo_") || !strings.Contains(message, "3") {
+		t.Error("The error message does not contain the label and the index of the record.")
+	}
+}
+
+func TestInvalidEXIError(t *testing.T) {
+	err := &senml.InvalidEXIError{
+		Reason: "reason",
+	}
+	message := err.Error()
+	if !strings.Contains(message, "reason") {
+		t.Error("The error message does not contain the reason.")
+	}
+}
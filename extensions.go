@@ -0,0 +1,220 @@
+package senml
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MustUnderstandError is an error which is returned when a record contains an unknown label ending with "_".
+// Such labels must be understood by the recipient, so the record must not be processed (RFC 8428 chapter 4.4).
+type MustUnderstandError struct {
+	// The unknown label
+	Label string
+}
+
+func (err *MustUnderstandError) Error() string {
+	return fmt.Sprintf("The record contains the unknown label %q which must be understood", err.Label)
+}
+
+func newMustUnderstandError(label string) *MustUnderstandError {
+	return &MustUnderstandError{
+		Label: label,
+	}
+}
+
+// UnsupportedExtensionValueError is an error which is returned when the value of an extension can't be encoded.
+type UnsupportedExtensionValueError struct {
+	// The label of the extension
+	Label string
+
+	// The value which can't be encoded
+	Value interface{}
+}
+
+func (err *UnsupportedExtensionValueError) Error() string {
+	return fmt.Sprintf("The value of the extension %q has the unsupported type %T", err.Label, err.Value)
+}
+
+func newUnsupportedExtensionValueError(label string, value interface{}) *UnsupportedExtensionValueError {
+	return &UnsupportedExtensionValueError{
+		Label: label,
+		Value: value,
+	}
+}
+
+// the labels of the fields of a record, which are not stored as extensions
+var recordLabels = map[string]bool{
+	"bn": true, "bt": true, "bu": true, "bv": true, "bs": true, "bver": true,
+	"n": true, "u": true, "v": true, "vb": true, "vs": true, "vd": true, "s": true, "t": true, "ut": true,
+	"bct": true, "ct": true,
+}
+
+// recordFields has the same fields as Record but not its methods, so it can be marshalled using the struct tags
+type recordFields Record
+
+// MarshalJSON encodes the record as a JSON object and appends its extensions.
+func (record Record) MarshalJSON() ([]byte, error) {
+	encodedRecord, err := json.Marshal(recordFields(record))
+	if err != nil || len(record.Extensions) == 0 {
+		return encodedRecord, err
+	}
+	var buffer bytes.Buffer
+	buffer.Write(encodedRecord[:len(encodedRecord)-1])
+	for i, label := range sortedExtensionLabels(record.Extensions) {
+		encodedLabel, _ := json.Marshal(label)
+		encodedValue, err := json.Marshal(record.Extensions[label])
+		if err != nil {
+			return nil, newUnsupportedExtensionValueError(label, record.Extensions[label])
+		}
+		if i > 0 || len(encodedRecord) > 2 {
+			buffer.WriteByte(',')
+		}
+		buffer.Write(encodedLabel)
+		buffer.WriteByte(':')
+		buffer.Write(encodedValue)
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalJSON decodes the record from a JSON object. Unknown and registered labels are kept in the extensions of the record.
+// Numbers of unknown labels are kept as json.Number, so they are written back without losing precision.
+func (record *Record) UnmarshalJSON(data []byte) error {
+	var fields recordFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	for label, encodedValue := range values {
+		if recordLabels[label] {
+			continue
+		}
+		var value interface{}
+		var decoder = json.NewDecoder(bytes.NewReader(encodedValue))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+		if registration, ok := lookupLabelByName(label); ok {
+			if err := json.Unmarshal(encodedValue, &value); err != nil {
+				return err
+			}
+			convertedValue, ok := registration.convert(value)
+			if !ok {
+				return newUnsupportedExtensionValueError(label, value)
+			}
+			value = convertedValue
+		}
+		if fields.Extensions == nil {
+			fields.Extensions = map[string]interface{}{}
+		}
+		fields.Extensions[label] = value
+	}
+	*record = Record(fields)
+	return nil
+}
+
+// MarshalXML encodes the record as an XML element and writes its extensions as additional attributes.
+func (record Record) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	if start.Name.Space == "" && start.Name.Local == "Record" {
+		// encoding/xml uses the name of the type if the record is marshalled on its own
+		start.Name.Local = "senml"
+	}
+	for _, label := range sortedExtensionLabels(record.Extensions) {
+		var value string
+		switch extension := record.Extensions[label].(type) {
+		case string:
+			value = extension
+		case float64:
+			value = strconv.FormatFloat(extension, 'g', -1, 64)
+		case json.Number:
+			value = extension.String()
+		case bool, int, int64, uint64:
+			value = fmt.Sprint(extension)
+		default:
+			return newUnsupportedExtensionValueError(label, extension)
+		}
+		var attribute = xmlAttributeName(label)
+		if registration, ok := lookupLabelByName(label); ok {
+			attribute = xml.Name{Local: registration.xmlAttribute()}
+		}
+		start.Attr = append(start.Attr, xml.Attr{Name: attribute, Value: value})
+	}
+	return encoder.EncodeElement(recordFields(record), start)
+}
+
+// UnmarshalXML decodes the record from an XML element. Unknown attributes are kept as string extensions of the record, attributes of registered labels are converted to the type of the label.
+// Attributes in a namespace are keyed as "{namespace}name", e.g. "{http://example.com/acme}fw".
+func (record *Record) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	var fields recordFields
+	if err := decoder.DecodeElement(&fields, &start); err != nil {
+		return err
+	}
+	for _, attribute := range start.Attr {
+		if attribute.Name.Space == "xmlns" || attribute.Name.Local == "xmlns" {
+			continue
+		}
+		if attribute.Name.Space != "" {
+			if fields.Extensions == nil {
+				fields.Extensions = map[string]interface{}{}
+			}
+			fields.Extensions["{"+attribute.Name.Space+"}"+attribute.Name.Local] = attribute.Value
+			continue
+		}
+		if recordLabels[attribute.Name.Local] {
+			continue
+		}
+		var label = attribute.Name.Local
+		var value interface{} = attribute.Value
+		if registration, ok := lookupLabelByXMLAttribute(attribute.Name.Local); ok {
+			label = registration.name()
+			if value, ok = registration.parse(attribute.Value); !ok {
+				return newUnsupportedExtensionValueError(label, attribute.Value)
+			}
+		}
+		if fields.Extensions == nil {
+			fields.Extensions = map[string]interface{}{}
+		}
+		fields.Extensions[label] = value
+	}
+	*record = Record(fields)
+	return nil
+}
+
+// xmlAttributeName returns the name of the XML attribute of an extension label, which may contain a namespace as "{namespace}name"
+func xmlAttributeName(label string) xml.Name {
+	if strings.HasPrefix(label, "{") {
+		if end := strings.Index(label, "}"); end > 0 {
+			return xml.Name{Space: label[1:end], Local: label[end+1:]}
+		}
+	}
+	return xml.Name{Local: label}
+}
+
+// sortedExtensionLabels returns the labels of the extensions in a stable order
+func sortedExtensionLabels(extensions map[string]interface{}) []string {
+	var labels = make([]string, 0, len(extensions))
+	for label := range extensions {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// validateExtensions returns an error if an extension of the record must be understood but is not registered
+func validateExtensions(record Record) *MustUnderstandError {
+	for _, label := range sortedExtensionLabels(record.Extensions) {
+		if _, registered := lookupLabelByName(label); !registered && strings.HasSuffix(label, "_") {
+			return newMustUnderstandError(label)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package senml_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	senml "github.com/nkristek/go-senml"
+)
+
+func TestExtensionsJSONRoundTrip(t *testing.T) {
+	const data = `[{"bn":"urn:dev:ow:10e2073a01080063:","n":"temperature","v":23.1,"loc":{"lat":60.07965,"lon":24.30621},"vendor":"acme"},{"n":"humidity","v":80}]`
+	message, err := senml.Decode([]byte(data), senml.JSON)
+	if err != nil {
+		t.Error("Decoding JSON failed: ", err)
+		return
+	}
+
+	var expected = map[string]interface{}{
+		"loc":    map[string]interface{}{"lat": json.Number("60.07965"), "lon": json.Number("24.30621")},
+		"vendor": "acme",
+	}
+	if !reflect.DeepEqual(message.Records[0].Extensions, expected) {
+		t.Errorf("The extensions were not decoded: %v", message.Records[0].Extensions)
+	}
+	if message.Records[1].Extensions != nil {
+		t.Error("A record without unknown fields should not have extensions")
+	}
+
+	encodedMessage, err := message.Encode(senml.JSON)
+	if err != nil {
+		t.Error("Encoding JSON failed: ", err)
+		return
+	}
+	if string(encodedMessage) != data {
+		t.Errorf("The extensions were not encoded. expected: %s, got: %s", data, encodedMessage)
+	}
+}
+
+func TestExtensionsJSONNumbers(t *testing.T) {
+	const data = `[{"n":"temperature","v":23.1,"ratio":1e-7,"serial":12345678901234567891}]`
+	message, err := senml.Decode([]byte(data), senml.JSON)
+	if err != nil {
+		t.Error("Decoding JSON failed: ", err)
+		return
+	}
+	if message.Records[0].Extensions["serial"] != json.Number("12345678901234567891") {
+		t.Errorf("The number was not kept: %#v", message.Records[0].Extensions["serial"])
+	}
+
+	encodedMessage, err := message.Encode(senml.JSON)
+	if err != nil {
+		t.Error("Encoding JSON failed: ", err)
+		return
+	}
+	if string(encodedMessage) != data {
+		t.Errorf("The numbers were not written back verbatim. expected: %s, got: %s", data, encodedMessage)
+	}
+
+	encodedMessage, err = message.Encode(senml.CBOR)
+	if err != nil {
+		t.Error("Encoding CBOR failed: ", err)
+		return
+	}
+	decodedMessage, err := senml.Decode(encodedMessage, senml.CBOR)
+	if err != nil {
+		t.Error("Decoding CBOR failed: ", err)
+		return
+	}
+	if decodedMessage.Records[0].Extensions["serial"] != uint64(12345678901234567891) || decodedMessage.Records[0].Extensions["ratio"] != 1e-7 {
+		t.Errorf("The numbers were not encoded as CBOR numbers: %v", decodedMessage.Records[0].Extensions)
+	}
+}
+
+func TestExtensionsOnEmptyRecord(t *testing.T) {
+	var record = senml.Record{
+		Extensions: map[string]interface{}{"vendor": "acme"},
+	}
+	encodedMessage, err := senml.Message{Records: []senml.Record{record}}.Encode(senml.JSON)
+	if err != nil {
+		t.Error("Encoding JSON failed: ", err)
+		return
+	}
+	if string(encodedMessage) != `[{"vendor":"acme"}]` {
+		t.Errorf("Encoding a record with only extensions resulted in %s", encodedMessage)
+	}
+}
+
+func TestExtensionsXMLRoundTrip(t *testing.T) {
+	const data = `<sensml xmlns="urn:ietf:params:xml:ns:senml"><senml vendor="acme" n="temperature" v="23.1"></senml></sensml>`
+	message, err := senml.Decode([]byte(data), senml.XML)
+	if err != nil {
+		t.Error("Decoding XML failed: ", err)
+		return
+	}
+	if !reflect.DeepEqual(message.Records[0].Extensions, map[string]interface{}{"vendor": "acme"}) {
+		t.Errorf("The extensions were not decoded: %v", message.Records[0].Extensions)
+	}
+
+	encodedMessage, err := message.Encode(senml.XML)
+	if err != nil {
+		t.Error("Encoding XML failed: ", err)
+		return
+	}
+	if string(encodedMessage) != data {
+		t.Errorf("The extensions were not encoded. expected: %s, got: %s", data, encodedMessage)
+	}
+}
+
+func TestExtensionsXMLNamespace(t *testing.T) {
+	const data = `<sensml xmlns="urn:ietf:params:xml:ns:senml" xmlns:acme="http://example.com/acme"><senml n="temperature" v="23.1" acme:fw="1.2"></senml></sensml>`
+	message, err := senml.Decode([]byte(data), senml.XML)
+	if err != nil {
+		t.Error("Decoding XML failed: ", err)
+		return
+	}
+	var expected = map[string]interface{}{"{http://example.com/acme}fw": "1.2"}
+	if !reflect.DeepEqual(message.Records[0].Extensions, expected) {
+		t.Errorf("The namespaced attribute was not decoded: %v", message.Records[0].Extensions)
+		return
+	}
+
+	encodedMessage, err := message.Encode(senml.XML)
+	if err != nil {
+		t.Error("Encoding XML failed: ", err)
+		return
+	}
+	if !strings.Contains(string(encodedMessage), `="http://example.com/acme"`) || !strings.Contains(string(encodedMessage), `:fw="1.2"`) {
+		t.Errorf("The namespaced attribute was not encoded: %s", encodedMessage)
+	}
+	decodedMessage, err := senml.Decode(encodedMessage, senml.XML)
+	if err != nil {
+		t.Error("Decoding XML failed: ", err)
+		return
+	}
+	if !reflect.DeepEqual(decodedMessage, message) {
+		t.Errorf("The namespaced attribute changed while encoding and decoding XML: %s", encodedMessage)
+	}
+}
+
+func TestExtensionsCBORRoundTrip(t *testing.T) {
+	// [{0: "temperature", 2: 23, 40: 1, "vendor": "acme"}]
+	data, _ := hex.DecodeString("81a4006b74656d706572617475726502171828016676656e646f726461636d65")
+	message, err := senml.Decode(data, senml.CBOR)
+	if err != nil {
+		t.Error("Decoding CBOR failed: ", err)
+		return
+	}
+	var expected = map[string]interface{}{
+		"40":     int64(1),
+		"vendor": "acme",
+	}
+	if !reflect.DeepEqual(message.Records[0].Extensions, expected) {
+		t.Errorf("The extensions were not decoded: %v", message.Records[0].Extensions)
+	}
+
+	encodedMessage, err := message.Encode(senml.CBOR)
+	if err != nil {
+		t.Error("Encoding CBOR failed: ", err)
+		return
+	}
+	decodedMessage, err := senml.Decode(encodedMessage, senml.CBOR)
+	if err != nil {
+		t.Error("Decoding CBOR failed: ", err)
+		return
+	}
+	if !reflect.DeepEqual(message, decodedMessage) {
+		t.Errorf("The extensions changed while encoding and decoding CBOR: %x", encodedMessage)
+	}
+}
+
+func TestExtensionsUnsupportedValue(t *testing.T) {
+	var record = senml.Record{
+		Extensions: map[string]interface{}{"vendor": struct{}{}},
+	}
+	for _, format := range []senml.EncodingFormat{senml.XML, senml.CBOR} {
+		_, err := senml.Message{Records: []senml.Record{record}}.Encode(format)
+		if err == nil || !strings.Contains(err.Error(), "vendor") {
+			t.Errorf("Encoding an unsupported extension value in format %v should result in an error, got: %v", format, err)
+		}
+	}
+}
+
+func TestResolveKeepsExtensions(t *testing.T) {
+	const data = `[{"bn":"test:","n":"temperature","v":23.1,"vendor":"acme"}]`
+	message, err := senml.Decode([]byte(data), senml.JSON)
+	if err != nil {
+		t.Error("Decoding JSON failed: ", err)
+		return
+	}
+
+	resolvedMessage, err := message.Resolve()
+	if err != nil {
+		t.Error("Resolving a record with an extension should not result in an error: ", err)
+		return
+	}
+	if !reflect.DeepEqual(resolvedMessage.Records[0].Extensions, message.Records[0].Extensions) {
+		t.Error("The extensions were not kept while resolving")
+	}
+}
+
+func TestResolveMustUnderstand(t *testing.T) {
+	const data = `[{"n":"temperature","v":23.1,"batt_":1}]`
+	message, err := senml.Decode([]byte(data), senml.JSON)
+	if err != nil {
+		t.Error("Decoding JSON failed: ", err)
+		return
+	}
+
+	_, err = message.Resolve()
+	mustUnderstandError, ok := err.(*senml.MustUnderstandError)
+	if !ok {
+		t.Error("Resolving a record with an unknown must understand label should result in a MustUnderstandError, got: ", err)
+		return
+	}
+	if mustUnderstandError.Label != "batt_" {
+		t.Error("The error contains an unexpected label")
+	}
+
+	validationError, ok := message.Validate().(*senml.ValidationError)
+	if !ok || len(validationError.Errors) != 1 || validationError.Errors[0].Field != "batt_" {
+		t.Error("Validating a record with an unknown must understand label should report the label")
+	}
+}
+
+func TestMustUnderstandError(t *testing.T) {
+	err := &senml.MustUnderstandError{
+		Label: "batt_",
+	}
+	message := err.Error()
+	if !strings.Contains(message, "batt_") {
+		t.Error("The error message does not contain the label.")
+	}
+}
+
+func TestUnsupportedExtensionValueError(t *testing.T) {
+	err := &senml.UnsupportedExtensionValueError{
+		Label: "vendor",
+		Value: struct{}{},
+	}
+	message := err.Error()
+	if !strings.Contains(message, "vendor") {
+		t.Error("The error message does not contain the label.")
+	}
+}
@@ -0,0 +1,103 @@
+package senml
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Features is the set of SenML features used by a message as defined in RFC 9100. Every bit of the version number stands for a feature,
+// the bits 0 to 3 are reserved since they form the version number 10 of RFC 8428.
+type Features uint
+
+const (
+	// FeatureSecondaryUnits means that the message may use the secondary units of RFC 8798 (feature code 4)
+	FeatureSecondaryUnits Features = 1 << 4
+)
+
+// SupportedFeatures declares the features supported by this library
+const SupportedFeatures = FeatureSecondaryUnits
+
+// the names of the registered features by their feature code
+var featureNames = map[int]string{
+	4: "secondary units",
+}
+
+// UnsupportedFeaturesError is an error which is returned when the version number of the message contains features which are not supported by this library (RFC 9100).
+type UnsupportedFeaturesError struct {
+	// The features currently supported by this library
+	SupportedFeatures Features
+
+	// The version of the given message
+	GivenVersion int
+
+	// The features of the given message which are not supported
+	UnsupportedFeatures Features
+}
+
+func (err *UnsupportedFeaturesError) Error() string {
+	return fmt.Sprintf("The message uses unsupported features. (version: %v, unsupported features: %v)", err.GivenVersion, err.UnsupportedFeatures)
+}
+
+func newUnsupportedFeaturesError(givenVersion int, unsupportedFeatures Features) *UnsupportedFeaturesError {
+	return &UnsupportedFeaturesError{
+		SupportedFeatures:   SupportedFeatures,
+		GivenVersion:        givenVersion,
+		UnsupportedFeatures: unsupportedFeatures,
+	}
+}
+
+// FeaturesFromVersion returns the features used by a message with the given version number.
+// Version numbers up to 10 are versions of RFC 8428 and earlier drafts, which don't use any features.
+// Above 10, the bits 0 to 3 are reserved and must form the version number 10 (RFC 9100 chapter 2). Reserved bits which differ from 10 are
+// returned as the feature codes 0 to 3, so e.g. 11 (bit 0 set), 16 (bits 1 and 3 cleared) and 18 (bit 3 cleared) are rejected by Resolve,
+// while 26 (bit 4, secondary units) is accepted.
+func FeaturesFromVersion(version int) Features {
+	if version <= SupportedVersion {
+		return 0
+	}
+	return Features(version ^ SupportedVersion)
+}
+
+// Version returns the version number of a message which uses the features.
+func (features Features) Version() int {
+	return SupportedVersion ^ int(features)
+}
+
+// Has returns true if all of the given features are set.
+func (features Features) Has(feature Features) bool {
+	return features&feature == feature
+}
+
+// FeatureCodes returns the feature codes, which are the positions of the set bits, in ascending order.
+func (features Features) FeatureCodes() []int {
+	var featureCodes []int
+	for featureCode := 0; features>>uint(featureCode) != 0; featureCode++ {
+		if features&(1<<uint(featureCode)) != 0 {
+			featureCodes = append(featureCodes, featureCode)
+		}
+	}
+	return featureCodes
+}
+
+func (features Features) String() string {
+	var names []string
+	for _, featureCode := range features.FeatureCodes() {
+		if name, ok := featureNames[featureCode]; ok {
+			names = append(names, name)
+		} else {
+			names = append(names, fmt.Sprintf("feature code %v", featureCode))
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// validateVersion returns an error if the version number contains features which are not supported
+func validateVersion(version int) *UnsupportedFeaturesError {
+	if unsupportedFeatures := FeaturesFromVersion(version) &^ SupportedFeatures; unsupportedFeatures != 0 {
+		return newUnsupportedFeaturesError(version, unsupportedFeatures)
+	}
+	return nil
+}
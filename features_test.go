@@ -0,0 +1,144 @@
+package senml_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	senml "github.com/nkristek/go-senml"
+)
+
+func TestFeaturesFromVersion(t *testing.T) {
+	var tests = []struct {
+		version  int
+		features senml.Features
+	}{
+		{5, 0},
+		{10, 0},
+		{11, 1},
+		{16, senml.FeatureSecondaryUnits | 1<<3 | 1<<1},
+		{18, senml.FeatureSecondaryUnits | 1<<3},
+		{26, senml.FeatureSecondaryUnits},
+		{58, senml.FeatureSecondaryUnits | 1<<5},
+	}
+	for _, test := range tests {
+		if features := senml.FeaturesFromVersion(test.version); features != test.features {
+			t.Errorf("The version %v resulted in the features %v, expected: %v", test.version, features, test.features)
+		}
+	}
+
+	if senml.FeatureSecondaryUnits.Version() != 26 {
+		t.Error("The version of the secondary units feature should be 26")
+	}
+	if !senml.SupportedFeatures.Has(senml.FeatureSecondaryUnits) || senml.Features(0).Has(senml.FeatureSecondaryUnits) {
+		t.Error("Has returned an unexpected result")
+	}
+}
+
+func TestFeatureCodes(t *testing.T) {
+	var features = senml.FeatureSecondaryUnits | 1<<5 | 1
+	if !reflect.DeepEqual(features.FeatureCodes(), []int{0, 4, 5}) {
+		t.Errorf("Unexpected feature codes: %v", features.FeatureCodes())
+	}
+	if features.String() != "feature code 0, secondary units, feature code 5" {
+		t.Errorf("Unexpected string representation: %v", features)
+	}
+	if senml.Features(0).String() != "none" {
+		t.Errorf("Unexpected string representation of no features: %v", senml.Features(0))
+	}
+}
+
+func TestResolveSupportedFeatures(t *testing.T) {
+	var version = senml.FeatureSecondaryUnits.Version()
+	var name = "test"
+	var unit = "km/h"
+	var value float64 = 1
+	message := senml.Message{
+		Records: []senml.Record{
+			{BaseVersion: &version, Name: &name, Unit: &unit, Value: &value},
+		},
+	}
+
+	resolvedMessage, err := message.Resolve()
+	if err != nil {
+		t.Error("Resolving a message with supported features failed: ", err)
+		return
+	}
+	if resolvedMessage.Records[0].BaseVersion == nil || *resolvedMessage.Records[0].BaseVersion != version {
+		t.Error("The features of the message should be kept on the resolved records")
+	}
+	if err := message.Validate(); err != nil {
+		t.Error("Validating a message with supported features failed: ", err)
+	}
+}
+
+func TestResolveUnsupportedFeatures(t *testing.T) {
+	var version = (senml.FeatureSecondaryUnits | 1<<5).Version()
+	var name = "test"
+	var value float64 = 1
+	message := senml.Message{
+		Records: []senml.Record{
+			{BaseVersion: &version, Name: &name, Value: &value},
+		},
+	}
+
+	_, err := message.Resolve()
+	unsupportedFeaturesError, ok := err.(*senml.UnsupportedFeaturesError)
+	if !ok {
+		t.Error("Resolving a message with unsupported features should result in an UnsupportedFeaturesError, got: ", err)
+		return
+	}
+	if unsupportedFeaturesError.UnsupportedFeatures != 1<<5 || unsupportedFeaturesError.GivenVersion != version {
+		t.Errorf("The error reports unexpected features: %v", unsupportedFeaturesError)
+	}
+
+	validationError, ok := message.Validate().(*senml.ValidationError)
+	if !ok || len(validationError.Errors) != 1 || validationError.Errors[0].Field != "bver" {
+		t.Error("Validating a message with unsupported features should report the version")
+		return
+	}
+	if _, ok := validationError.Errors[0].Err.(*senml.UnsupportedFeaturesError); !ok {
+		t.Error("Validating a message with unsupported features should result in an UnsupportedFeaturesError")
+	}
+}
+
+func TestResolveMalformedReservedBits(t *testing.T) {
+	var name = "test"
+	var value float64 = 1
+	for _, test := range []struct {
+		version             int
+		unsupportedFeatures senml.Features
+	}{
+		{11, 1},
+		{16, 1<<3 | 1<<1},
+		{18, 1 << 3},
+	} {
+		var version = test.version
+		message := senml.Message{
+			Records: []senml.Record{
+				{BaseVersion: &version, Name: &name, Value: &value},
+			},
+		}
+		_, err := message.Resolve()
+		unsupportedFeaturesError, ok := err.(*senml.UnsupportedFeaturesError)
+		if !ok {
+			t.Errorf("Resolving a message with version %v should result in an UnsupportedFeaturesError, got: %v", version, err)
+			continue
+		}
+		if unsupportedFeaturesError.UnsupportedFeatures != test.unsupportedFeatures {
+			t.Errorf("The error for version %v reports unexpected features: %v", version, unsupportedFeaturesError.UnsupportedFeatures)
+		}
+	}
+}
+
+func TestUnsupportedFeaturesErrorMessage(t *testing.T) {
+	err := &senml.UnsupportedFeaturesError{
+		SupportedFeatures:   senml.SupportedFeatures,
+		GivenVersion:        42,
+		UnsupportedFeatures: 1 << 5,
+	}
+	message := err.Error()
+	if !strings.Contains(message, "42") || !strings.Contains(message, "feature code 5") {
+		t.Error("The error message does not contain the version and the unsupported features.")
+	}
+}
@@ -0,0 +1,73 @@
+package senml
+
+import (
+	"sort"
+	"time"
+)
+
+// Index is a view of a resolved message which groups the records by their resolved name.
+// The records of every name are sorted chronologically, records without a time come first.
+type Index struct {
+	records map[string][]Record
+	names   []string
+}
+
+// Index groups the records of the resolved message by their name. The message is not modified.
+func (message Message) Index() *Index {
+	var index = &Index{
+		records: map[string][]Record{},
+	}
+	for _, record := range message.Records {
+		var name string
+		if record.Name != nil {
+			name = *record.Name
+		}
+		if _, ok := index.records[name]; !ok {
+			index.names = append(index.names, name)
+		}
+		index.records[name] = append(index.records[name], record)
+	}
+	sort.Strings(index.names)
+	for _, records := range index.records {
+		sortRecordsChronologically(records)
+	}
+	return index
+}
+
+// Names returns the resolved names of the records in lexical order.
+func (index *Index) Names() []string {
+	var names = make([]string, len(index.names))
+	copy(names, index.names)
+	return names
+}
+
+// Records returns the records with the given resolved name sorted chronologically. The returned slice must not be modified.
+func (index *Index) Records(name string) []Record {
+	return index.records[name]
+}
+
+// Latest returns the record with the given resolved name which has the latest time. It returns false if there is no record with this name.
+// If multiple records have the latest time, the last of them in the message is returned.
+func (index *Index) Latest(name string) (Record, bool) {
+	var records = index.records[name]
+	if len(records) == 0 {
+		return Record{}, false
+	}
+	return records[len(records)-1], true
+}
+
+// Range returns the records with the given resolved name whose time is in the half-open interval [from, to), sorted chronologically.
+// Records without a time are never returned. The returned slice must not be modified.
+func (index *Index) Range(name string, from time.Time, to time.Time) []Record {
+	var records = index.records[name]
+	var start = sort.Search(len(records), func(i int) bool {
+		return records[i].Time != nil && !secondsToTime(*records[i].Time).Before(from)
+	})
+	var end = sort.Search(len(records), func(i int) bool {
+		return records[i].Time != nil && !secondsToTime(*records[i].Time).Before(to)
+	})
+	if start >= end {
+		return nil
+	}
+	return records[start:end]
+}
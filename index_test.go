@@ -0,0 +1,90 @@
+package senml_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	senml "github.com/nkristek/go-senml"
+)
+
+func TestIndex(t *testing.T) {
+	message, err := senml.Decode([]byte(jsonData), senml.JSON)
+	if err != nil {
+		t.Error("Decoding JSON failed: ", err)
+		return
+	}
+	resolvedMessage, err := message.Resolve()
+	if err != nil {
+		t.Error("Resolving the message failed: ", err)
+		return
+	}
+
+	var index = resolvedMessage.Index()
+	if !reflect.DeepEqual(index.Names(), []string{"urn:dev:ow:10e2073a01080063"}) {
+		t.Errorf("The index contains unexpected names: %v", index.Names())
+	}
+	var records = index.Records("urn:dev:ow:10e2073a01080063")
+	if len(records) != len(resolvedMessage.Records) {
+		t.Error("The index doesn't contain all records of the name")
+		return
+	}
+	for i := 1; i < len(records); i++ {
+		if *records[i].Time < *records[i-1].Time {
+			t.Error("The records of the name are not sorted chronologically")
+		}
+	}
+	if len(index.Records("unknown")) != 0 {
+		t.Error("An unknown name should not have records")
+	}
+}
+
+func TestIndexLatest(t *testing.T) {
+	const data = `[{"bn":"urn:dev:ow:10e2073a01080063:","bt":1.320067464e+09,"n":"temp","v":20,"t":60},{"n":"temp","v":21},{"n":"humidity","v":80}]`
+	message, err := senml.Decode([]byte(data), senml.JSON)
+	if err != nil {
+		t.Error("Decoding JSON failed: ", err)
+		return
+	}
+	resolvedMessage, err := message.Resolve()
+	if err != nil {
+		t.Error("Resolving the message failed: ", err)
+		return
+	}
+
+	var index = resolvedMessage.Index()
+	if !reflect.DeepEqual(index.Names(), []string{"urn:dev:ow:10e2073a01080063:humidity", "urn:dev:ow:10e2073a01080063:temp"}) {
+		t.Errorf("The index contains unexpected names: %v", index.Names())
+	}
+	latest, ok := index.Latest("urn:dev:ow:10e2073a01080063:temp")
+	if !ok || *latest.Value != 20 {
+		t.Error("The record with the latest time was not returned")
+	}
+	if _, ok := index.Latest("unknown"); ok {
+		t.Error("An unknown name should not have a latest record")
+	}
+}
+
+func TestIndexRange(t *testing.T) {
+	var name = "urn:dev:ow:10e2073a01080063:temp"
+	var message senml.Message
+	for i := 0; i < 10; i++ {
+		var value = float64(i)
+		var recordTime = float64(1320067464 + i*60)
+		message.Records = append(message.Records, senml.Record{Name: &name, Value: &value, Time: &recordTime})
+	}
+
+	var index = message.Index()
+	var from = time.Unix(1320067464+2*60, 0)
+	var to = time.Unix(1320067464+5*60, 0)
+	var records = index.Range(name, from, to)
+	if len(records) != 3 || *records[0].Value != 2 || *records[2].Value != 4 {
+		t.Errorf("The range contains unexpected records: %v", records)
+	}
+	if len(index.Range(name, to, from)) != 0 {
+		t.Error("An empty range should not contain records")
+	}
+	if len(index.Range("unknown", from, to)) != 0 {
+		t.Error("An unknown name should not have records in the range")
+	}
+}
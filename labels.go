@@ -0,0 +1,356 @@
+package senml
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// LabelType declares the Go type of the values of a registered label
+type LabelType int
+
+const (
+	// FloatLabel values are float64
+	FloatLabel LabelType = iota
+
+	// StringLabel values are string
+	StringLabel
+
+	// BoolLabel values are bool
+	BoolLabel
+)
+
+// BaseResolution declares how the base variant of a registered label is applied while resolving
+type BaseResolution int
+
+const (
+	// NoBase means the label has no base variant
+	NoBase BaseResolution = iota
+
+	// InheritBase uses the base value if the record has no value, like the BaseUnit
+	InheritBase
+
+	// AddBase adds the base value to the value, like the BaseValue. Only valid for FloatLabel.
+	AddBase
+
+	// PrependBase prepends the base value to the value, like the BaseName. Only valid for StringLabel.
+	PrependBase
+)
+
+// Label describes a custom SenML label (RFC 8428 chapter 12.2). Values of registered labels are kept in the Extensions of a record using the JSON key of the label,
+// they are decoded to the Go type of the label and encoded using the JSON key, XML attribute and CBOR label of the registration.
+type Label struct {
+	// The JSON key of the label, e.g. "loc". Labels ending with "_" must be understood, registering them marks them as understood.
+	Name string
+
+	// The XML attribute of the label. Defaults to Name if empty.
+	XMLAttribute string
+
+	// The CBOR integer label
+	CBORLabel int
+
+	// The Go type of the values
+	Type LabelType
+
+	// How the base variant of the label is applied while resolving
+	BaseResolution BaseResolution
+
+	// The JSON key of the base variant, e.g. "bloc". Required if BaseResolution is not NoBase.
+	BaseName string
+
+	// The XML attribute of the base variant. Defaults to BaseName if empty.
+	BaseXMLAttribute string
+
+	// The CBOR integer label of the base variant. Required if BaseResolution is not NoBase.
+	BaseCBORLabel int
+}
+
+// LabelRegistrationError is an error which is returned when a label can't be registered.
+type LabelRegistrationError struct {
+	// The JSON key of the label
+	Name string
+
+	// The reason why the label can't be registered
+	Reason string
+}
+
+func (err *LabelRegistrationError) Error() string {
+	return fmt.Sprintf("The label %q can't be registered: %v", err.Name, err.Reason)
+}
+
+func newLabelRegistrationError(name string, reason string) *LabelRegistrationError {
+	return &LabelRegistrationError{
+		Name:   name,
+		Reason: reason,
+	}
+}
+
+// the CBOR labels of the fields of a record
+var recordCBORLabels = map[int]bool{
+	cborLabelBaseVersion: true, cborLabelBaseName: true, cborLabelBaseTime: true, cborLabelBaseUnit: true, cborLabelBaseValue: true, cborLabelBaseSum: true,
+	cborLabelName: true, cborLabelUnit: true, cborLabelValue: true, cborLabelStringValue: true, cborLabelBoolValue: true,
+	cborLabelSum: true, cborLabelTime: true, cborLabelUpdateTime: true, cborLabelDataValue: true,
+	cborLabelBaseContentType: true, cborLabelContentType: true,
+}
+
+// labelKey contains the keys of a label or its base variant in all formats
+type labelKey struct {
+	name         string
+	xmlAttribute string
+	cborLabel    int
+}
+
+// registeredLabel is a single key of a registered label, which is either the label itself or its base variant
+type registeredLabel struct {
+	label Label
+	base  bool
+}
+
+var labelRegistry = struct {
+	sync.RWMutex
+	labels     map[string]Label
+	byName     map[string]registeredLabel
+	byXML      map[string]registeredLabel
+	byCBOR     map[int]registeredLabel
+	cborLabels map[string]int
+}{
+	labels:     map[string]Label{},
+	byName:     map[string]registeredLabel{},
+	byXML:      map[string]registeredLabel{},
+	byCBOR:     map[int]registeredLabel{},
+	cborLabels: map[string]int{},
+}
+
+// RegisterLabel registers a custom label, so Decode, Encode and Resolve handle it in all formats except EXI.
+// The JSON keys, XML attributes and CBOR labels must not collide with the fields of a record or other registered labels.
+func RegisterLabel(label Label) error {
+	if label.XMLAttribute == "" {
+		label.XMLAttribute = label.Name
+	}
+	if label.BaseXMLAttribute == "" {
+		label.BaseXMLAttribute = label.BaseName
+	}
+	if label.Name == "" {
+		return newLabelRegistrationError(label.Name, "the name is empty")
+	}
+	switch label.BaseResolution {
+	case NoBase:
+	case InheritBase:
+	case AddBase:
+		if label.Type != FloatLabel {
+			return newLabelRegistrationError(label.Name, "the base value can only be added to float values")
+		}
+	case PrependBase:
+		if label.Type != StringLabel {
+			return newLabelRegistrationError(label.Name, "the base value can only be prepended to string values")
+		}
+	default:
+		return newLabelRegistrationError(label.Name, "unknown base resolution")
+	}
+	if label.BaseResolution != NoBase && label.BaseName == "" {
+		return newLabelRegistrationError(label.Name, "the name of the base variant is empty")
+	}
+
+	labelRegistry.Lock()
+	defer labelRegistry.Unlock()
+	var keys = []labelKey{
+		{label.Name, label.XMLAttribute, label.CBORLabel},
+	}
+	if label.BaseResolution != NoBase {
+		keys = append(keys, labelKey{label.BaseName, label.BaseXMLAttribute, label.BaseCBORLabel})
+		if label.BaseName == label.Name || label.BaseXMLAttribute == label.XMLAttribute || label.BaseCBORLabel == label.CBORLabel {
+			return newLabelRegistrationError(label.Name, "the base variant has the same name or label")
+		}
+	}
+	for _, key := range keys {
+		if _, ok := labelRegistry.byName[key.name]; ok || recordLabels[key.name] {
+			return newLabelRegistrationError(label.Name, fmt.Sprintf("the name %q is already used", key.name))
+		}
+		if _, ok := labelRegistry.byXML[key.xmlAttribute]; ok || recordLabels[key.xmlAttribute] {
+			return newLabelRegistrationError(label.Name, fmt.Sprintf("the XML attribute %q is already used", key.xmlAttribute))
+		}
+		if _, ok := labelRegistry.byCBOR[key.cborLabel]; ok || recordCBORLabels[key.cborLabel] {
+			return newLabelRegistrationError(label.Name, fmt.Sprintf("the CBOR label %v is already used", key.cborLabel))
+		}
+	}
+	for i, key := range keys {
+		var registration = registeredLabel{label: label, base: i > 0}
+		labelRegistry.byName[key.name] = registration
+		labelRegistry.byXML[key.xmlAttribute] = registration
+		labelRegistry.byCBOR[key.cborLabel] = registration
+		labelRegistry.cborLabels[key.name] = key.cborLabel
+	}
+	labelRegistry.labels[label.Name] = label
+	return nil
+}
+
+// UnregisterLabel removes a registered label and its base variant. It returns false if no label with the given JSON key is registered.
+func UnregisterLabel(name string) bool {
+	labelRegistry.Lock()
+	defer labelRegistry.Unlock()
+	label, ok := labelRegistry.labels[name]
+	if !ok {
+		return false
+	}
+	delete(labelRegistry.labels, name)
+	delete(labelRegistry.byName, label.Name)
+	delete(labelRegistry.byXML, label.XMLAttribute)
+	delete(labelRegistry.byCBOR, label.CBORLabel)
+	delete(labelRegistry.cborLabels, label.Name)
+	if label.BaseResolution != NoBase {
+		delete(labelRegistry.byName, label.BaseName)
+		delete(labelRegistry.byXML, label.BaseXMLAttribute)
+		delete(labelRegistry.byCBOR, label.BaseCBORLabel)
+		delete(labelRegistry.cborLabels, label.BaseName)
+	}
+	return true
+}
+
+// LookupLabel returns the registered label with the given JSON key. It returns false if the label is not registered.
+func LookupLabel(name string) (Label, bool) {
+	labelRegistry.RLock()
+	defer labelRegistry.RUnlock()
+	label, ok := labelRegistry.labels[name]
+	return label, ok
+}
+
+// Labels returns all registered labels sorted by their JSON key.
+func Labels() []Label {
+	labelRegistry.RLock()
+	defer labelRegistry.RUnlock()
+	var labels = make([]Label, 0, len(labelRegistry.labels))
+	for _, label := range labelRegistry.labels {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+	return labels
+}
+
+func lookupLabelByName(name string) (registeredLabel, bool) {
+	labelRegistry.RLock()
+	defer labelRegistry.RUnlock()
+	registration, ok := labelRegistry.byName[name]
+	return registration, ok
+}
+
+func lookupLabelByXMLAttribute(attribute string) (registeredLabel, bool) {
+	labelRegistry.RLock()
+	defer labelRegistry.RUnlock()
+	registration, ok := labelRegistry.byXML[attribute]
+	return registration, ok
+}
+
+func lookupLabelByCBORLabel(cborLabel int64) (registeredLabel, bool) {
+	labelRegistry.RLock()
+	defer labelRegistry.RUnlock()
+	registration, ok := labelRegistry.byCBOR[int(cborLabel)]
+	return registration, ok
+}
+
+func lookupCBORLabel(name string) (int, bool) {
+	labelRegistry.RLock()
+	defer labelRegistry.RUnlock()
+	cborLabel, ok := labelRegistry.cborLabels[name]
+	return cborLabel, ok
+}
+
+// name returns the JSON key of the registered label or its base variant
+func (registration registeredLabel) name() string {
+	if registration.base {
+		return registration.label.BaseName
+	}
+	return registration.label.Name
+}
+
+// xmlAttribute returns the XML attribute of the registered label or its base variant
+func (registration registeredLabel) xmlAttribute() string {
+	if registration.base {
+		return registration.label.BaseXMLAttribute
+	}
+	return registration.label.XMLAttribute
+}
+
+// convert converts a decoded value to the type of the label. It returns false if the value has a different type.
+func (registration registeredLabel) convert(value interface{}) (interface{}, bool) {
+	switch registration.label.Type {
+	case FloatLabel:
+		switch value := value.(type) {
+		case float64:
+			return value, true
+		case int64:
+			return float64(value), true
+		}
+	case StringLabel:
+		if value, ok := value.(string); ok {
+			return value, true
+		}
+	case BoolLabel:
+		if value, ok := value.(bool); ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// parse converts the value of an XML attribute to the type of the label
+func (registration registeredLabel) parse(value string) (interface{}, bool) {
+	switch registration.label.Type {
+	case FloatLabel:
+		parsedValue, err := strconv.ParseFloat(value, 64)
+		return parsedValue, err == nil
+	case BoolLabel:
+		parsedValue, err := strconv.ParseBool(value)
+		return parsedValue, err == nil
+	}
+	return value, true
+}
+
+// resolveExtensions applies the base variants of the registered labels to the extensions of the record.
+// The base values are kept in the given map across records. The base variants are not part of the resolved extensions.
+func resolveExtensions(baseExtensions map[string]interface{}, extensions map[string]interface{}) map[string]interface{} {
+	var resolvedExtensions map[string]interface{}
+	for label, value := range extensions {
+		if registration, ok := lookupLabelByName(label); ok && registration.base {
+			baseExtensions[registration.label.Name] = value
+			continue
+		}
+		if resolvedExtensions == nil {
+			resolvedExtensions = map[string]interface{}{}
+		}
+		resolvedExtensions[label] = value
+	}
+	for name, baseValue := range baseExtensions {
+		label, ok := LookupLabel(name)
+		if !ok {
+			continue
+		}
+		value, hasValue := resolvedExtensions[name]
+		if resolvedExtensions == nil {
+			resolvedExtensions = map[string]interface{}{}
+		}
+		switch label.BaseResolution {
+		case InheritBase:
+			if !hasValue {
+				resolvedExtensions[name] = baseValue
+			}
+		case AddBase:
+			var resolvedValue, _ = baseValue.(float64)
+			if hasValue {
+				floatValue, _ := value.(float64)
+				resolvedValue += floatValue
+			}
+			resolvedExtensions[name] = resolvedValue
+		case PrependBase:
+			var resolvedValue, _ = baseValue.(string)
+			if hasValue {
+				stringValue, _ := value.(string)
+				resolvedValue += stringValue
+			}
+			resolvedExtensions[name] = resolvedValue
+		}
+	}
+	return resolvedExtensions
+}
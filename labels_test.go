@@ -0,0 +1,217 @@
+package senml_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+
+	senml "github.com/nkristek/go-senml"
+)
+
+func registerTestLabels(t *testing.T) func() {
+	var labels = []senml.Label{
+		{
+			Name:      "batt_",
+			CBORLabel: 100,
+			Type:      senml.FloatLabel,
+		},
+		{
+			Name:             "loc",
+			XMLAttribute:     "location",
+			CBORLabel:        101,
+			Type:             senml.StringLabel,
+			BaseResolution:   senml.InheritBase,
+			BaseName:         "bloc",
+			BaseXMLAttribute: "baselocation",
+			BaseCBORLabel:    -101,
+		},
+		{
+			Name:           "off",
+			CBORLabel:      102,
+			Type:           senml.FloatLabel,
+			BaseResolution: senml.AddBase,
+			BaseName:       "boff",
+			BaseCBORLabel:  -102,
+		},
+	}
+	for _, label := range labels {
+		if err := senml.RegisterLabel(label); err != nil {
+			t.Fatal("Registering the label failed: ", err)
+		}
+	}
+	return func() {
+		for _, label := range labels {
+			senml.UnregisterLabel(label.Name)
+		}
+	}
+}
+
+func TestRegisteredLabelsJSON(t *testing.T) {
+	defer registerTestLabels(t)()
+
+	const data = `[{"bloc":"kitchen","boff":10,"n":"temperature","v":23.1,"batt_":98,"off":1},{"n":"humidity","v":80,"loc":"cellar"}]`
+	message, err := senml.Decode([]byte(data), senml.JSON)
+	if err != nil {
+		t.Error("Decoding JSON failed: ", err)
+		return
+	}
+	if message.Records[0].Extensions["batt_"] != float64(98) || message.Records[0].Extensions["bloc"] != "kitchen" {
+		t.Errorf("The registered labels were not decoded: %v", message.Records[0].Extensions)
+	}
+
+	resolvedMessage, err := message.ResolveWithOptions(senml.ResolveOptions{KeepOrder: true})
+	if err != nil {
+		t.Error("Resolving a record with a registered must understand label should not result in an error: ", err)
+		return
+	}
+	var expected = []map[string]interface{}{
+		{"batt_": float64(98), "loc": "kitchen", "off": float64(11)},
+		{"loc": "cellar", "off": float64(10)},
+	}
+	for i, record := range resolvedMessage.Records {
+		if !reflect.DeepEqual(record.Extensions, expected[i]) {
+			t.Errorf("The registered labels of record %v were not resolved: %v", i, record.Extensions)
+		}
+	}
+
+	_, err = senml.Decode([]byte(`[{"n":"temperature","v":23.1,"batt_":"full"}]`), senml.JSON)
+	if _, ok := err.(*senml.UnsupportedExtensionValueError); !ok {
+		t.Error("Decoding a registered label with an unexpected type should result in an UnsupportedExtensionValueError, got: ", err)
+	}
+}
+
+func TestRegisteredLabelsXML(t *testing.T) {
+	defer registerTestLabels(t)()
+
+	const data = `<sensml xmlns="urn:ietf:params:xml:ns:senml"><senml batt_="98" baselocation="kitchen" n="temperature" v="23.1"></senml></sensml>`
+	message, err := senml.Decode([]byte(data), senml.XML)
+	if err != nil {
+		t.Error("Decoding XML failed: ", err)
+		return
+	}
+	var expected = map[string]interface{}{"batt_": float64(98), "bloc": "kitchen"}
+	if !reflect.DeepEqual(message.Records[0].Extensions, expected) {
+		t.Errorf("The registered labels were not decoded: %v", message.Records[0].Extensions)
+	}
+
+	encodedMessage, err := message.Encode(senml.XML)
+	if err != nil {
+		t.Error("Encoding XML failed: ", err)
+		return
+	}
+	if string(encodedMessage) != data {
+		t.Errorf("The registered labels were not encoded. expected: %s, got: %s", data, encodedMessage)
+	}
+
+	_, err = senml.Decode([]byte(`<sensml xmlns="urn:ietf:params:xml:ns:senml"><senml batt_="full" n="temperature" v="23.1"></senml></sensml>`), senml.XML)
+	if _, ok := err.(*senml.UnsupportedExtensionValueError); !ok {
+		t.Error("Decoding a registered label with an invalid value should result in an UnsupportedExtensionValueError, got: ", err)
+	}
+}
+
+func TestRegisteredLabelsCBOR(t *testing.T) {
+	defer registerTestLabels(t)()
+
+	// [{0: "temperature", 2: 23, 100: 98, -101: "kitchen"}]
+	data, _ := hex.DecodeString("81a4006b74656d70657261747572650217186418623864676b69746368656e")
+	message, err := senml.Decode(data, senml.CBOR)
+	if err != nil {
+		t.Error("Decoding CBOR failed: ", err)
+		return
+	}
+	var expected = map[string]interface{}{"batt_": float64(98), "bloc": "kitchen"}
+	if !reflect.DeepEqual(message.Records[0].Extensions, expected) {
+		t.Errorf("The registered labels were not decoded: %v", message.Records[0].Extensions)
+	}
+
+	encodedMessage, err := message.Encode(senml.CBOR)
+	if err != nil {
+		t.Error("Encoding CBOR failed: ", err)
+		return
+	}
+	if !bytes.Contains(encodedMessage, []byte{0x18, 0x64}) || !bytes.Contains(encodedMessage, []byte{0x38, 0x64}) {
+		t.Errorf("The registered labels were not encoded with their CBOR labels: %x", encodedMessage)
+	}
+	decodedMessage, err := senml.Decode(encodedMessage, senml.CBOR)
+	if err != nil {
+		t.Error("Decoding CBOR failed: ", err)
+		return
+	}
+	if !reflect.DeepEqual(message, decodedMessage) {
+		t.Errorf("The registered labels changed while encoding and decoding CBOR: %x", encodedMessage)
+	}
+
+	// [{0: "temperature", 2: 23, 100: "full"}]
+	data, _ = hex.DecodeString("81a3006b74656d7065726174757265021718646466756c6c")
+	_, err = senml.Decode(data, senml.CBOR)
+	if _, ok := err.(*senml.InvalidCBORError); !ok {
+		t.Error("Decoding a registered label with an unexpected type should result in an InvalidCBORError, got: ", err)
+	}
+}
+
+func TestRegisterLabelInvalid(t *testing.T) {
+	defer registerTestLabels(t)()
+
+	var invalidLabels = map[string]senml.Label{
+		"empty name":               {CBORLabel: 200},
+		"record field":             {Name: "bn", CBORLabel: 200},
+		"record CBOR label":        {Name: "test", CBORLabel: 8},
+		"registered name":          {Name: "loc", CBORLabel: 200},
+		"registered XML attribute": {Name: "test", XMLAttribute: "location", CBORLabel: 200},
+		"registered CBOR label":    {Name: "test", CBORLabel: -101},
+		"missing base name":        {Name: "test", CBORLabel: 200, BaseResolution: senml.InheritBase, BaseCBORLabel: 201},
+		"add to string":            {Name: "test", CBORLabel: 200, Type: senml.StringLabel, BaseResolution: senml.AddBase, BaseName: "btest", BaseCBORLabel: 201},
+		"prepend to float":         {Name: "test", CBORLabel: 200, BaseResolution: senml.PrependBase, BaseName: "btest", BaseCBORLabel: 201},
+		"same base CBOR label":     {Name: "test", CBORLabel: 200, BaseResolution: senml.InheritBase, BaseName: "btest", BaseCBORLabel: 200},
+	}
+	for name, label := range invalidLabels {
+		err := senml.RegisterLabel(label)
+		if _, ok := err.(*senml.LabelRegistrationError); !ok {
+			t.Errorf("Registering an invalid label (%v) should result in a LabelRegistrationError, got: %v", name, err)
+		}
+	}
+	if _, ok := senml.LookupLabel("test"); ok {
+		t.Error("An invalid label was registered")
+	}
+}
+
+func TestLookupAndUnregisterLabel(t *testing.T) {
+	var unregister = registerTestLabels(t)
+
+	label, ok := senml.LookupLabel("loc")
+	if !ok || label.XMLAttribute != "location" || label.BaseName != "bloc" {
+		t.Errorf("The registered label was not found: %+v", label)
+	}
+	if len(senml.Labels()) != 3 || senml.Labels()[0].Name != "batt_" {
+		t.Error("The registered labels are not sorted")
+	}
+
+	unregister()
+	if _, ok := senml.LookupLabel("loc"); ok {
+		t.Error("The label was not unregistered")
+	}
+	if senml.UnregisterLabel("loc") {
+		t.Error("Unregistering a label twice should return false")
+	}
+	message, err := senml.Decode([]byte(`[{"n":"temperature","v":23.1,"batt_":98}]`), senml.JSON)
+	if err != nil {
+		t.Error("Decoding JSON failed: ", err)
+		return
+	}
+	if _, err := message.Resolve(); err == nil {
+		t.Error("An unregistered must understand label should result in an error")
+	}
+}
+
+func TestLabelRegistrationError(t *testing.T) {
+	err := &senml.LabelRegistrationError{
+		Name:   "loc",
+		Reason: "reason",
+	}
+	message := err.Error()
+	if !strings.Contains(message, "loc") || !strings.Contains(message, "reason") {
+		t.Error("The error message does not contain the name and reason.")
+	}
+}
@@ -0,0 +1,425 @@
+package senml
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// the separator between the name segments of nested structs
+const marshalNameSeparator = "/"
+
+// InvalidMarshalTypeError is an error which is returned when Marshal is called with a value which is not a struct or Unmarshal with a value which is not a non-nil pointer to a struct.
+type InvalidMarshalTypeError struct {
+	// The given type
+	Type reflect.Type
+}
+
+func (err *InvalidMarshalTypeError) Error() string {
+	return fmt.Sprintf("The type %v can't be mapped to SenML records, expected a struct or a pointer to a struct", err.Type)
+}
+
+func newInvalidMarshalTypeError(t reflect.Type) *InvalidMarshalTypeError {
+	return &InvalidMarshalTypeError{
+		Type: t,
+	}
+}
+
+// UnsupportedFieldTypeError is an error which is returned when a tagged struct field has a type which can't be mapped to a value of a record.
+type UnsupportedFieldTypeError struct {
+	// The name of the struct field
+	Field string
+
+	// The type of the struct field
+	Type reflect.Type
+}
+
+func (err *UnsupportedFieldTypeError) Error() string {
+	return fmt.Sprintf("The field %v has the unsupported type %v", err.Field, err.Type)
+}
+
+func newUnsupportedFieldTypeError(field string, t reflect.Type) *UnsupportedFieldTypeError {
+	return &UnsupportedFieldTypeError{
+		Field: field,
+		Type:  t,
+	}
+}
+
+// UnmarshalValueError is an error which is returned when the value of a record can't be stored in the matching struct field.
+type UnmarshalValueError struct {
+	// The resolved name of the record
+	Name string
+
+	// The name of the struct field
+	Field string
+
+	// The type of the struct field
+	Type reflect.Type
+}
+
+func (err *UnmarshalValueError) Error() string {
+	return fmt.Sprintf("The value of the record %q can't be stored in the field %v of type %v", err.Name, err.Field, err.Type)
+}
+
+func newUnmarshalValueError(name string, field string, t reflect.Type) *UnmarshalValueError {
+	return &UnmarshalValueError{
+		Name:  name,
+		Field: field,
+		Type:  t,
+	}
+}
+
+// RecursiveTypeError is an error which is returned when a struct contains itself through tagged fields, e.g. a linked list, since the names of its records would be unbounded.
+type RecursiveTypeError struct {
+	// The struct type which contains itself
+	Type reflect.Type
+}
+
+func (err *RecursiveTypeError) Error() string {
+	return fmt.Sprintf("The type %v contains itself and can't be mapped to SenML records", err.Type)
+}
+
+func newRecursiveTypeError(t reflect.Type) *RecursiveTypeError {
+	return &RecursiveTypeError{
+		Type: t,
+	}
+}
+
+// taggedField is a struct field with a senml tag
+type taggedField struct {
+	// the name of the struct field
+	field string
+
+	// the name segment of the tag
+	name string
+
+	// the unit option of the tag
+	unit string
+
+	value reflect.Value
+}
+
+var byteSliceType = reflect.TypeOf([]byte(nil))
+
+// Marshal returns a message with one record per struct field with a senml tag, e.g. `senml:"temp,unit=Cel"`.
+// Numbers are stored in the Value, booleans in the BoolValue, strings in the StringValue and []byte in the DataValue of the record.
+// Fields of nested structs are named by the name segments joined with "/", nil pointers are omitted.
+// The base name is set on the first record if it is not empty.
+func Marshal(v interface{}, baseName string) (Message, error) {
+	var value = reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return Message{}, newInvalidMarshalTypeError(reflect.TypeOf(v))
+	}
+	if err := checkRecursiveType(value.Type(), nil); err != nil {
+		return Message{}, err
+	}
+	var message Message
+	if err := marshalStruct(value, "", &message.Records); err != nil {
+		return Message{}, err
+	}
+	if baseName != "" && len(message.Records) > 0 {
+		message.Records[0].BaseName = &baseName
+	}
+	return message, nil
+}
+
+func marshalStruct(value reflect.Value, prefix string, records *[]Record) error {
+	for _, field := range taggedFields(value) {
+		var fieldValue = field.value
+		if fieldValue.Kind() == reflect.Ptr {
+			if fieldValue.IsNil() {
+				continue
+			}
+			fieldValue = fieldValue.Elem()
+		}
+		var name = prefix + field.name
+		if fieldValue.Kind() == reflect.Struct {
+			if err := marshalStruct(fieldValue, name+marshalNameSeparator, records); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var record = Record{Name: &name}
+		if field.unit != "" {
+			var unit = field.unit
+			record.Unit = &unit
+		}
+		switch fieldValue.Kind() {
+		case reflect.Float32, reflect.Float64:
+			var number = fieldValue.Float()
+			record.Value = &number
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			var number = float64(fieldValue.Int())
+			record.Value = &number
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			var number = float64(fieldValue.Uint())
+			record.Value = &number
+		case reflect.Bool:
+			var boolValue = fieldValue.Bool()
+			record.BoolValue = &boolValue
+		case reflect.String:
+			var stringValue = fieldValue.String()
+			record.StringValue = &stringValue
+		default:
+			if fieldValue.Type() != byteSliceType {
+				return newUnsupportedFieldTypeError(field.field, fieldValue.Type())
+			}
+			record.SetDataValueBytes(fieldValue.Bytes())
+		}
+		*records = append(*records, record)
+	}
+	return nil
+}
+
+// Unmarshal stores the values of the records of the resolved message in the struct fields with a senml tag, v must be a non-nil pointer to a struct.
+// A field matches a record if the resolved name of the record equals the path of the field or ends with it after a ":", so base names like "urn:dev:ow:10e2073a01080063:" are ignored.
+// Every record is assigned to the longest matching path, so e.g. "outdoor/temp" doesn't fill a top-level "temp" field.
+// If multiple records match a field, the record with the latest time is used. Fields without a matching record are not modified.
+// If the unit of the tag differs from the unit of the record, the value is converted to the unit of the tag (see ConvertUnit).
+func Unmarshal(message Message, v interface{}) error {
+	var value = reflect.ValueOf(v)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return newInvalidMarshalTypeError(reflect.TypeOf(v))
+	}
+	if err := checkRecursiveType(value.Elem().Type(), nil); err != nil {
+		return err
+	}
+	var paths = make(map[string]bool)
+	collectFieldPaths(value.Elem(), "", paths)
+	var index = message.Index()
+	var matcher = recordMatcher{
+		index: index,
+		names: assignRecordNames(index.names, paths),
+	}
+	_, err := matcher.unmarshalStruct(value.Elem(), "")
+	return err
+}
+
+// recordMatcher keeps the resolved names of the records which are assigned to the path of a field
+type recordMatcher struct {
+	index *Index
+
+	// the resolved names of the records by the path of the field they are assigned to
+	names map[string][]string
+}
+
+// collectFieldPaths adds the paths of all tagged fields which hold a value, including the fields of nil pointers to nested structs
+func collectFieldPaths(value reflect.Value, prefix string, paths map[string]bool) {
+	for _, field := range taggedFields(value) {
+		var name = prefix + field.name
+		var fieldType = field.value.Type()
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType.Kind() != reflect.Struct {
+			paths[name] = true
+			continue
+		}
+		var target = field.value
+		if target.Kind() == reflect.Ptr {
+			if target.IsNil() {
+				target = reflect.New(fieldType)
+			}
+			target = target.Elem()
+		}
+		collectFieldPaths(target, name+marshalNameSeparator, paths)
+	}
+}
+
+// assignRecordNames assigns every resolved name to the longest path which equals the name or the part of the name after a ":"
+func assignRecordNames(resolvedNames []string, paths map[string]bool) map[string][]string {
+	var names = make(map[string][]string)
+	for _, resolvedName := range resolvedNames {
+		var candidate = resolvedName
+		for {
+			if paths[candidate] {
+				names[candidate] = append(names[candidate], resolvedName)
+				break
+			}
+			var separator = strings.Index(candidate, ":")
+			if separator < 0 {
+				break
+			}
+			candidate = candidate[separator+1:]
+		}
+	}
+	return names
+}
+
+// unmarshalStruct stores the values of the matching records in the fields of the struct. It returns true if at least one field matched a record.
+func (matcher recordMatcher) unmarshalStruct(value reflect.Value, prefix string) (matched bool, err error) {
+	for _, field := range taggedFields(value) {
+		var name = prefix + field.name
+		var fieldValue = field.value
+		var fieldType = fieldValue.Type()
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		var target = fieldValue
+		if fieldValue.Kind() == reflect.Ptr {
+			if fieldValue.IsNil() {
+				// nil pointers are only set if a record matches
+				target = reflect.New(fieldType).Elem()
+			} else {
+				target = fieldValue.Elem()
+			}
+		}
+
+		var fieldMatched bool
+		if fieldType.Kind() == reflect.Struct {
+			fieldMatched, err = matcher.unmarshalStruct(target, name+marshalNameSeparator)
+		} else {
+			fieldMatched, err = matcher.unmarshalField(name, field, target)
+		}
+		if err != nil {
+			return
+		}
+		if fieldMatched {
+			if fieldValue.Kind() == reflect.Ptr && fieldValue.IsNil() {
+				fieldValue.Set(target.Addr())
+			}
+			matched = true
+		}
+	}
+	return
+}
+
+// unmarshalField stores the value of the matching record in the field. It returns false if no record matches.
+func (matcher recordMatcher) unmarshalField(name string, field taggedField, fieldValue reflect.Value) (bool, error) {
+	record, ok := matcher.latestRecord(name)
+	if !ok {
+		return false, nil
+	}
+	if field.unit != "" && record.Unit != nil && *record.Unit != field.unit {
+		convertedRecord, err := record.ConvertUnit(field.unit)
+		if err != nil {
+			return false, err
+		}
+		record = convertedRecord
+	}
+	return true, unmarshalValue(record, field.field, fieldValue)
+}
+
+func unmarshalValue(record Record, field string, fieldValue reflect.Value) error {
+	var valueError = newUnmarshalValueError(*record.Name, field, fieldValue.Type())
+	switch fieldValue.Kind() {
+	case reflect.Float32, reflect.Float64:
+		if record.Value == nil || fieldValue.OverflowFloat(*record.Value) {
+			return valueError
+		}
+		fieldValue.SetFloat(*record.Value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if record.Value == nil || *record.Value != float64(int64(*record.Value)) || fieldValue.OverflowInt(int64(*record.Value)) {
+			return valueError
+		}
+		fieldValue.SetInt(int64(*record.Value))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if record.Value == nil || *record.Value < 0 || *record.Value != float64(uint64(*record.Value)) || fieldValue.OverflowUint(uint64(*record.Value)) {
+			return valueError
+		}
+		fieldValue.SetUint(uint64(*record.Value))
+	case reflect.Bool:
+		if record.BoolValue == nil {
+			return valueError
+		}
+		fieldValue.SetBool(*record.BoolValue)
+	case reflect.String:
+		if record.StringValue == nil {
+			return valueError
+		}
+		fieldValue.SetString(*record.StringValue)
+	default:
+		if fieldValue.Type() != byteSliceType {
+			return newUnsupportedFieldTypeError(field, fieldValue.Type())
+		}
+		if record.DataValue == nil {
+			return valueError
+		}
+		data, err := record.DataValueBytes()
+		if err != nil {
+			return err
+		}
+		fieldValue.SetBytes(data)
+	}
+	return nil
+}
+
+// latestRecord returns the record with the latest time of the records which are assigned to the path
+func (matcher recordMatcher) latestRecord(path string) (Record, bool) {
+	var latest Record
+	var found = false
+	for _, resolvedName := range matcher.names[path] {
+		record, _ := matcher.index.Latest(resolvedName)
+		if !found || (record.Time != nil && (latest.Time == nil || *record.Time > *latest.Time)) {
+			latest = record
+			found = true
+		}
+	}
+	return latest, found
+}
+
+// checkRecursiveType returns a RecursiveTypeError if the struct type contains one of the struct types on the path or itself through the fields returned by taggedFields
+func checkRecursiveType(structType reflect.Type, path []reflect.Type) error {
+	for _, pathType := range path {
+		if pathType == structType {
+			return newRecursiveTypeError(structType)
+		}
+	}
+	path = append(path, structType)
+	for i := 0; i < structType.NumField(); i++ {
+		var structField = structType.Field(i)
+		tag, hasTag := structField.Tag.Lookup("senml")
+		var fieldType = structField.Type
+		var embedded = structField.Anonymous && !hasTag && fieldType.Kind() == reflect.Struct
+		if !embedded && (!hasTag || tag == "-" || structField.PkgPath != "") {
+			continue
+		}
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType.Kind() != reflect.Struct {
+			continue
+		}
+		if err := checkRecursiveType(fieldType, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// taggedFields returns the exported fields of the struct which have a senml tag. Fields of embedded structs without a tag are included.
+func taggedFields(value reflect.Value) []taggedField {
+	var fields []taggedField
+	var structType = value.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		var structField = structType.Field(i)
+		tag, hasTag := structField.Tag.Lookup("senml")
+		if structField.Anonymous && !hasTag && structField.Type.Kind() == reflect.Struct {
+			fields = append(fields, taggedFields(value.Field(i))...)
+			continue
+		}
+		if !hasTag || tag == "-" || structField.PkgPath != "" {
+			continue
+		}
+		var options = strings.Split(tag, ",")
+		var field = taggedField{
+			field: structField.Name,
+			name:  options[0],
+			value: value.Field(i),
+		}
+		if field.name == "" {
+			field.name = structField.Name
+		}
+		for _, option := range options[1:] {
+			if strings.HasPrefix(option, "unit=") {
+				field.unit = strings.TrimPrefix(option, "unit=")
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
@@ -0,0 +1,257 @@
+package senml_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	senml "github.com/nkristek/go-senml"
+)
+
+type marshalLocation struct {
+	Latitude  float64 `senml:"lat,unit=lat"`
+	Longitude float64 `senml:"lon,unit=lon"`
+}
+
+type marshalWeather struct {
+	Temp       float64          `senml:"temp,unit=Cel"`
+	Humidity   int              `senml:"humidity,unit=%RH"`
+	Door       bool             `senml:"door"`
+	Label      string           `senml:"label"`
+	Raw        []byte           `senml:"raw"`
+	Location   marshalLocation  `senml:"location"`
+	Backup     *marshalLocation `senml:"backup"`
+	Pressure   *float64         `senml:"pressure,unit=Pa"`
+	Ignored    float64          `senml:"-"`
+	Untagged   float64
+	unexported float64 `senml:"unexported"`
+}
+
+func TestMarshal(t *testing.T) {
+	var weather = marshalWeather{
+		Temp:     23.5,
+		Humidity: 80,
+		Door:     true,
+		Label:    "Machine Room",
+		Raw:      []byte("hi"),
+		Location: marshalLocation{Latitude: 60.07965, Longitude: 24.30621},
+	}
+	message, err := senml.Marshal(&weather, "urn:dev:ow:10e2073a01080063:")
+	if err != nil {
+		t.Error("Marshalling the struct failed: ", err)
+		return
+	}
+
+	encodedMessage, err := message.Encode(senml.JSON)
+	if err != nil {
+		t.Error("Encoding JSON failed: ", err)
+		return
+	}
+	const expected = `[{"bn":"urn:dev:ow:10e2073a01080063:","n":"temp","u":"Cel","v":23.5},{"n":"humidity","u":"%RH","v":80},{"n":"door","vb":true},{"n":"label","vs":"Machine Room"},{"n":"raw","vd":"aGk"},{"n":"location/lat","u":"lat","v":60.07965},{"n":"location/lon","u":"lon","v":24.30621}]`
+	if string(encodedMessage) != expected {
+		t.Errorf("Marshalling the struct resulted in an unexpected message. expected: %s, got: %s", expected, encodedMessage)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	var pressure = 1013.25
+	var weather = marshalWeather{
+		Temp:     23.5,
+		Humidity: 80,
+		Door:     true,
+		Label:    "Machine Room",
+		Raw:      []byte("hi"),
+		Location: marshalLocation{Latitude: 60.07965, Longitude: 24.30621},
+		Backup:   &marshalLocation{Latitude: 1, Longitude: 2},
+		Pressure: &pressure,
+	}
+	message, err := senml.Marshal(weather, "urn:dev:ow:10e2073a01080063:")
+	if err != nil {
+		t.Error("Marshalling the struct failed: ", err)
+		return
+	}
+	resolvedMessage, err := message.Resolve()
+	if err != nil {
+		t.Error("Resolving the message failed: ", err)
+		return
+	}
+
+	var unmarshalledWeather = marshalWeather{Untagged: 5}
+	if err := senml.Unmarshal(resolvedMessage, &unmarshalledWeather); err != nil {
+		t.Error("Unmarshalling the message failed: ", err)
+		return
+	}
+	weather.Untagged = 5
+	if !reflect.DeepEqual(weather, unmarshalledWeather) {
+		t.Errorf("Unmarshalling the message resulted in an unexpected struct: %+v", unmarshalledWeather)
+	}
+}
+
+func TestUnmarshalLatestAndConversion(t *testing.T) {
+	const data = `[{"bn":"urn:dev:ow:10e2073a01080063:","bt":1.320067464e+09,"n":"temp","u":"Cel","v":23.5,"t":60},{"n":"temp","u":"Cel","v":21},{"n":"pressure","u":"hPa","v":1013.25}]`
+	message, err := senml.Decode([]byte(data), senml.JSON)
+	if err != nil {
+		t.Error("Decoding JSON failed: ", err)
+		return
+	}
+	resolvedMessage, err := message.Resolve()
+	if err != nil {
+		t.Error("Resolving the message failed: ", err)
+		return
+	}
+
+	var weather marshalWeather
+	if err := senml.Unmarshal(resolvedMessage, &weather); err != nil {
+		t.Error("Unmarshalling the message failed: ", err)
+		return
+	}
+	if weather.Temp != 23.5 {
+		t.Error("The value of the record with the latest time should be used")
+	}
+	if weather.Pressure == nil || *weather.Pressure != 101325 {
+		t.Error("The value should be converted to the unit of the tag")
+	}
+	if weather.Backup != nil {
+		t.Error("A pointer to a nested struct without records should stay nil")
+	}
+}
+
+type marshalCollision struct {
+	Temp    float64 `senml:"temp"`
+	Outdoor struct {
+		Temp float64 `senml:"temp"`
+	} `senml:"outdoor"`
+	Indoor *struct {
+		Temp float64 `senml:"temp"`
+	} `senml:"indoor"`
+}
+
+func TestUnmarshalSameLeafName(t *testing.T) {
+	var collision marshalCollision
+	collision.Temp = 21
+	collision.Outdoor.Temp = 5
+	message, err := senml.Marshal(collision, "urn:dev:ow:10e2073a01080063:")
+	if err != nil {
+		t.Error("Marshalling the struct failed: ", err)
+		return
+	}
+	resolvedMessage, err := message.Resolve()
+	if err != nil {
+		t.Error("Resolving the message failed: ", err)
+		return
+	}
+
+	var unmarshalledCollision marshalCollision
+	if err := senml.Unmarshal(resolvedMessage, &unmarshalledCollision); err != nil {
+		t.Error("Unmarshalling the message failed: ", err)
+		return
+	}
+	if !reflect.DeepEqual(collision, unmarshalledCollision) {
+		t.Errorf("A nested field should not fill a top-level field with the same name: %+v", unmarshalledCollision)
+	}
+
+	const data = `[{"n":"urn:dev:ow:10e2073a01080063:indoor/temp","v":19},{"n":"urn:dev:ow:10e2073a01080063/temp","v":30}]`
+	message, err = senml.Decode([]byte(data), senml.JSON)
+	if err != nil {
+		t.Error("Decoding JSON failed: ", err)
+		return
+	}
+	unmarshalledCollision = marshalCollision{}
+	if err := senml.Unmarshal(message, &unmarshalledCollision); err != nil {
+		t.Error("Unmarshalling the message failed: ", err)
+		return
+	}
+	if unmarshalledCollision.Temp != 0 || unmarshalledCollision.Indoor == nil || unmarshalledCollision.Indoor.Temp != 19 {
+		t.Errorf("Only the part of the name after a \":\" should be matched: %+v", unmarshalledCollision)
+	}
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	const data = `[{"n":"door","v":1}]`
+	message, err := senml.Decode([]byte(data), senml.JSON)
+	if err != nil {
+		t.Error("Decoding JSON failed: ", err)
+		return
+	}
+
+	var weather marshalWeather
+	err = senml.Unmarshal(message, &weather)
+	unmarshalValueError, ok := err.(*senml.UnmarshalValueError)
+	if !ok {
+		t.Error("Unmarshalling a value into a field of another type should result in an UnmarshalValueError, got: ", err)
+		return
+	}
+	if unmarshalValueError.Name != "door" || unmarshalValueError.Field != "Door" {
+		t.Errorf("The error contains an unexpected record or field: %v", unmarshalValueError)
+	}
+
+	for _, v := range []interface{}{weather, nil, new(int)} {
+		if _, ok := senml.Unmarshal(message, v).(*senml.InvalidMarshalTypeError); !ok {
+			t.Errorf("Unmarshalling into %T should result in an InvalidMarshalTypeError", v)
+		}
+	}
+	if _, err := senml.Marshal(5, ""); err == nil {
+		t.Error("Marshalling a number should result in an error")
+	}
+	_, err = senml.Marshal(struct {
+		Values map[string]float64 `senml:"values"`
+	}{}, "")
+	if _, ok := err.(*senml.UnsupportedFieldTypeError); !ok {
+		t.Error("Marshalling a map should result in an UnsupportedFieldTypeError, got: ", err)
+	}
+}
+
+type marshalNode struct {
+	Temp float64      `senml:"temp"`
+	Next *marshalNode `senml:"next"`
+}
+
+type marshalTree struct {
+	Root struct {
+		Nodes marshalNode `senml:"nodes"`
+	} `senml:"root"`
+}
+
+func TestMarshalRecursiveType(t *testing.T) {
+	var node = marshalNode{Temp: 21}
+	node.Next = &node
+	for _, v := range []interface{}{node, marshalTree{}} {
+		_, err := senml.Marshal(v, "")
+		if _, ok := err.(*senml.RecursiveTypeError); !ok {
+			t.Errorf("Marshalling %T should result in a RecursiveTypeError, got: %v", v, err)
+		}
+	}
+
+	message, err := senml.Decode([]byte(`[{"n":"temp","v":21},{"n":"next/temp","v":22}]`), senml.JSON)
+	if err != nil {
+		t.Error("Decoding JSON failed: ", err)
+		return
+	}
+	var unmarshalledNode marshalNode
+	err = senml.Unmarshal(message, &unmarshalledNode)
+	recursiveTypeError, ok := err.(*senml.RecursiveTypeError)
+	if !ok {
+		t.Error("Unmarshalling into a recursive type should result in a RecursiveTypeError, got: ", err)
+		return
+	}
+	if recursiveTypeError.Type != reflect.TypeOf(marshalNode{}) {
+		t.Errorf("The error contains an unexpected type: %v", recursiveTypeError.Type)
+	}
+	if unmarshalledNode.Temp != 0 || unmarshalledNode.Next != nil {
+		t.Error("The struct should not be modified if its type is recursive")
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	var errors = map[string]error{
+		"int":         &senml.InvalidMarshalTypeError{Type: reflect.TypeOf(5)},
+		"Values":      &senml.UnsupportedFieldTypeError{Field: "Values", Type: reflect.TypeOf(map[string]float64{})},
+		"door":        &senml.UnmarshalValueError{Name: "door", Field: "Door", Type: reflect.TypeOf(true)},
+		"marshalNode": &senml.RecursiveTypeError{Type: reflect.TypeOf(marshalNode{})},
+	}
+	for expected, err := range errors {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("The error message of %T does not contain %q: %v", err, expected, err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package senml
+
+import (
+	"fmt"
+	"mime"
+)
+
+// mediaTypeInfo contains the media type and CoAP Content-Format ID of an encoding format (RFC 8428 chapter 12.3)
+type mediaTypeInfo struct {
+	mediaType     string
+	contentFormat int
+}
+
+var mediaTypes = map[EncodingFormat]mediaTypeInfo{
+	JSON:       {"application/senml+json", 110},
+	SenSMLJSON: {"application/sensml+json", 111},
+	CBOR:       {"application/senml+cbor", 112},
+	SenSMLCBOR: {"application/sensml+cbor", 113},
+	EXI:        {"application/senml-exi", 114},
+	SenSMLEXI:  {"application/sensml-exi", 115},
+	XML:        {"application/senml+xml", 310},
+	SenSMLXML:  {"application/sensml+xml", 311},
+}
+
+// UnsupportedMediaTypeError is an error which is returned when a media type is not a SenML or SenSML media type.
+type UnsupportedMediaTypeError struct {
+	// The given media type
+	MediaType string
+}
+
+func (err *UnsupportedMediaTypeError) Error() string {
+	return fmt.Sprintf("Unsupported media type: %q", err.MediaType)
+}
+
+func newUnsupportedMediaTypeError(mediaType string) *UnsupportedMediaTypeError {
+	return &UnsupportedMediaTypeError{
+		MediaType: mediaType,
+	}
+}
+
+// UnsupportedContentFormatError is an error which is returned when a CoAP Content-Format ID is not registered for SenML or SenSML.
+type UnsupportedContentFormatError struct {
+	// The given Content-Format ID
+	ContentFormat int
+}
+
+func (err *UnsupportedContentFormatError) Error() string {
+	return fmt.Sprintf("Unsupported content format: %v", err.ContentFormat)
+}
+
+func newUnsupportedContentFormatError(contentFormat int) *UnsupportedContentFormatError {
+	return &UnsupportedContentFormatError{
+		ContentFormat: contentFormat,
+	}
+}
+
+// MediaType returns the media type of the format, e.g. "application/senml+json". Returns an empty string if the format is unsupported.
+func (format EncodingFormat) MediaType() string {
+	return mediaTypes[format].mediaType
+}
+
+// ContentFormat returns the CoAP Content-Format ID of the format, e.g. 110 for JSON. Returns -1 if the format is unsupported.
+func (format EncodingFormat) ContentFormat() int {
+	info, ok := mediaTypes[format]
+	if !ok {
+		return -1
+	}
+	return info.contentFormat
+}
+
+// FormatFromMediaType returns the encoding format of the SenML or SenSML media type. Parameters and the case of the media type are ignored.
+func FormatFromMediaType(mediaType string) (EncodingFormat, error) {
+	parsedMediaType, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return 0, newUnsupportedMediaTypeError(mediaType)
+	}
+	for format, info := range mediaTypes {
+		if parsedMediaType == info.mediaType {
+			return format, nil
+		}
+	}
+	return 0, newUnsupportedMediaTypeError(mediaType)
+}
+
+// FormatFromContentFormat returns the encoding format of the CoAP Content-Format ID of a SenML or SenSML media type.
+func FormatFromContentFormat(contentFormat int) (EncodingFormat, error) {
+	for format, info := range mediaTypes {
+		if contentFormat == info.contentFormat {
+			return format, nil
+		}
+	}
+	return 0, newUnsupportedContentFormatError(contentFormat)
+}
@@ -0,0 +1,94 @@
+package senml_test
+
+import (
+	"strings"
+	"testing"
+
+	senml "github.com/nkristek/go-senml"
+)
+
+var mediaTypeTests = []struct {
+	format        senml.EncodingFormat
+	mediaType     string
+	contentFormat int
+}{
+	{senml.JSON, "application/senml+json", 110},
+	{senml.SenSMLJSON, "application/sensml+json", 111},
+	{senml.CBOR, "application/senml+cbor", 112},
+	{senml.SenSMLCBOR, "application/sensml+cbor", 113},
+	{senml.EXI, "application/senml-exi", 114},
+	{senml.SenSMLEXI, "application/sensml-exi", 115},
+	{senml.XML, "application/senml+xml", 310},
+	{senml.SenSMLXML, "application/sensml+xml", 311},
+}
+
+func TestMediaType(t *testing.T) {
+	for _, test := range mediaTypeTests {
+		if test.format.MediaType() != test.mediaType {
+			t.Errorf("The format %v has the unexpected media type %q", test.format, test.format.MediaType())
+		}
+		if test.format.ContentFormat() != test.contentFormat {
+			t.Errorf("The format %v has the unexpected content format %v", test.format, test.format.ContentFormat())
+		}
+	}
+
+	var unsupportedFormat = senml.EncodingFormat(999)
+	if unsupportedFormat.MediaType() != "" || unsupportedFormat.ContentFormat() != -1 {
+		t.Error("An unsupported format should not have a media type or content format")
+	}
+}
+
+func TestFormatFromMediaType(t *testing.T) {
+	for _, test := range mediaTypeTests {
+		format, err := senml.FormatFromMediaType(test.mediaType)
+		if err != nil || format != test.format {
+			t.Errorf("The media type %q resulted in the format %v, error: %v", test.mediaType, format, err)
+		}
+	}
+
+	format, err := senml.FormatFromMediaType("Application/SenML+JSON; charset=utf-8")
+	if err != nil || format != senml.JSON {
+		t.Errorf("Parameters and the case of the media type should be ignored, got format %v, error: %v", format, err)
+	}
+
+	for _, mediaType := range []string{"application/json", "", "application/senml-etch+json"} {
+		_, err := senml.FormatFromMediaType(mediaType)
+		if _, ok := err.(*senml.UnsupportedMediaTypeError); !ok {
+			t.Errorf("The media type %q should result in an UnsupportedMediaTypeError, got: %v", mediaType, err)
+		}
+	}
+}
+
+func TestFormatFromContentFormat(t *testing.T) {
+	for _, test := range mediaTypeTests {
+		format, err := senml.FormatFromContentFormat(test.contentFormat)
+		if err != nil || format != test.format {
+			t.Errorf("The content format %v resulted in the format %v, error: %v", test.contentFormat, format, err)
+		}
+	}
+
+	_, err := senml.FormatFromContentFormat(50)
+	if _, ok := err.(*senml.UnsupportedContentFormatError); !ok {
+		t.Error("An unknown content format should result in an UnsupportedContentFormatError, got: ", err)
+	}
+}
+
+func TestUnsupportedMediaTypeError(t *testing.T) {
+	err := &senml.UnsupportedMediaTypeError{
+		MediaType: "application/json",
+	}
+	message := err.Error()
+	if !strings.Contains(message, "application/json") {
+		t.Error("The error message does not contain the media type.")
+	}
+}
+
+func TestUnsupportedContentFormatError(t *testing.T) {
+	err := &senml.UnsupportedContentFormatError{
+		ContentFormat: 50,
+	}
+	message := err.Error()
+	if !strings.Contains(message, "50") {
+		t.Error("The error message does not contain the content format.")
+	}
+}
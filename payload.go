@@ -0,0 +1,118 @@
+package senml
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// payloadKind declares how the payload of a string or binary value is decoded
+type payloadKind int
+
+const (
+	textPayload payloadKind = iota
+	binaryPayload
+	jsonPayload
+	cborPayload
+)
+
+// the payload kinds of the CoAP Content-Format IDs supported by DecodePayload
+var contentFormatPayloadKinds = map[int]payloadKind{
+	0:  textPayload,
+	42: binaryPayload,
+	50: jsonPayload,
+	60: cborPayload,
+}
+
+// UnsupportedContentTypeError is an error which is returned when the payload of a record has a content type which can't be decoded.
+type UnsupportedContentTypeError struct {
+	// The content type of the record
+	ContentType string
+}
+
+func (err *UnsupportedContentTypeError) Error() string {
+	return fmt.Sprintf("The payload has the unsupported content type %q", err.ContentType)
+}
+
+func newUnsupportedContentTypeError(contentType string) *UnsupportedContentTypeError {
+	return &UnsupportedContentTypeError{
+		ContentType: contentType,
+	}
+}
+
+// DecodePayload decodes the StringValue or DataValue of the record according to its ContentType (RFC 9193).
+// JSON (50, application/json or +json) and CBOR (60, application/cbor or +cbor) payloads are decoded to the types of encoding/json,
+// text (0 or text/plain) results in a string and binary data (42 or application/octet-stream) in a []byte.
+// Without a content type, a StringValue results in a string and a DataValue in a []byte.
+// The record should be resolved, so the BaseContentType is taken into account.
+// Returns a MissingValueError if the record has neither a StringValue nor a DataValue.
+func (record Record) DecodePayload() (interface{}, error) {
+	var payload []byte
+	var kind payloadKind
+	switch {
+	case record.DataValue != nil:
+		data, err := record.DataValueBytes()
+		if err != nil {
+			return nil, err
+		}
+		payload, kind = data, binaryPayload
+	case record.StringValue != nil:
+		payload, kind = []byte(*record.StringValue), textPayload
+	default:
+		return nil, newMissingValueError()
+	}
+
+	if record.ContentType != nil {
+		var ok bool
+		if kind, ok = lookupPayloadKind(*record.ContentType); !ok {
+			return nil, newUnsupportedContentTypeError(*record.ContentType)
+		}
+	}
+
+	switch kind {
+	case textPayload:
+		return string(payload), nil
+	case jsonPayload:
+		var value interface{}
+		if err := json.Unmarshal(payload, &value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	case cborPayload:
+		var decoder = cborDecoder{data: payload}
+		value, err := decoder.readItem(1)
+		if err != nil {
+			return nil, err
+		}
+		if _, isBreak := value.(cborBreak); isBreak || decoder.offset != len(payload) {
+			return nil, newInvalidCBORError(decoder.offset, "the payload is not a single data item")
+		}
+		return cborExtensionValue(value), nil
+	}
+	return payload, nil
+}
+
+// lookupPayloadKind returns how payloads with the given content type or CoAP Content-Format ID are decoded
+func lookupPayloadKind(contentType string) (payloadKind, bool) {
+	if contentFormat, err := strconv.Atoi(contentType); err == nil {
+		kind, ok := contentFormatPayloadKinds[contentFormat]
+		return kind, ok
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return 0, false
+	}
+	switch {
+	case mediaType == "text/plain":
+		return textPayload, true
+	case mediaType == "application/octet-stream":
+		return binaryPayload, true
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return jsonPayload, true
+	case mediaType == "application/cbor" || strings.HasSuffix(mediaType, "+cbor"):
+		return cborPayload, true
+	}
+	return 0, false
+}
@@ -0,0 +1,183 @@
+package senml_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+
+	senml "github.com/nkristek/go-senml"
+)
+
+func TestContentTypeJSON(t *testing.T) {
+	const data = `[{"bn":"urn:dev:ow:10e2073a01080063:","bct":"50","n":"diagnostics","vd":"eyJlcnJvcnMiOjJ9"},{"n":"log","vs":"restarted","ct":"0"}]`
+	message, err := senml.Decode([]byte(data), senml.JSON)
+	if err != nil {
+		t.Error("Decoding JSON failed: ", err)
+		return
+	}
+	if message.Records[0].BaseContentType == nil || *message.Records[0].BaseContentType != "50" || *message.Records[1].ContentType != "0" {
+		t.Error("The content type fields were not decoded")
+		return
+	}
+	if message.Records[0].Extensions != nil {
+		t.Error("The content type fields should not be decoded as extensions")
+	}
+
+	encodedMessage, err := message.Encode(senml.JSON)
+	if err != nil {
+		t.Error("Encoding JSON failed: ", err)
+		return
+	}
+	if string(encodedMessage) != data {
+		t.Errorf("The content type fields were not encoded. expected: %s, got: %s", data, encodedMessage)
+	}
+
+	resolvedMessage, err := message.ResolveWithOptions(senml.ResolveOptions{KeepOrder: true})
+	if err != nil {
+		t.Error("Resolving the message failed: ", err)
+		return
+	}
+	for i, expected := range []string{"50", "0"} {
+		var record = resolvedMessage.Records[i]
+		if record.BaseContentType != nil || record.ContentType == nil || *record.ContentType != expected {
+			t.Errorf("The content type of record %v was not resolved", i)
+		}
+	}
+
+	resolvedRecords, err := message.ResolveRecords()
+	if err != nil {
+		t.Error("Resolving the records failed: ", err)
+		return
+	}
+	if resolvedRecords[0].ContentType != "50" {
+		t.Error("The content type of the typed record was not set")
+	}
+}
+
+func TestContentTypeXMLAndCBOR(t *testing.T) {
+	var baseContentType = "50"
+	var contentType = "text/plain;charset=utf-8"
+	var name = "log"
+	var stringValue = "restarted"
+	var message = senml.Message{
+		Records: []senml.Record{
+			{BaseContentType: &baseContentType, Name: &name, ContentType: &contentType, StringValue: &stringValue},
+		},
+	}
+
+	encodedMessage, err := message.Encode(senml.XML)
+	if err != nil {
+		t.Error("Encoding XML failed: ", err)
+		return
+	}
+	if !strings.Contains(string(encodedMessage), `bct="50"`) || !strings.Contains(string(encodedMessage), `ct="text/plain;charset=utf-8"`) {
+		t.Errorf("The content type fields were not encoded as XML attributes: %s", encodedMessage)
+	}
+	decodedMessage, err := senml.Decode(encodedMessage, senml.XML)
+	if err != nil || !reflect.DeepEqual(withoutXMLNames(message.Records), withoutXMLNames(decodedMessage.Records)) {
+		t.Error("The content type fields changed while encoding and decoding XML: ", err)
+	}
+
+	encodedMessage, err = message.Encode(senml.CBOR)
+	if err != nil {
+		t.Error("Encoding CBOR failed: ", err)
+		return
+	}
+	// -24: "50" and 27: "text/plain;charset=utf-8"
+	if !bytes.Contains(encodedMessage, []byte{0x37, 0x62, '5', '0'}) || !bytes.Contains(encodedMessage, []byte{0x18, 0x1b, 0x78, 0x18}) {
+		t.Errorf("The content type fields were not encoded with their CBOR labels: %x", encodedMessage)
+	}
+	decodedMessage, err = senml.Decode(encodedMessage, senml.CBOR)
+	if err != nil || !reflect.DeepEqual(message, decodedMessage) {
+		t.Error("The content type fields changed while encoding and decoding CBOR: ", err)
+	}
+}
+
+func TestContentTypeEXI(t *testing.T) {
+	var contentType = "50"
+	var name = "diagnostics"
+	var stringValue = `{"errors":2}`
+	for expectedLabel, record := range map[string]senml.Record{
+		"bct": {BaseContentType: &contentType, Name: &name, StringValue: &stringValue},
+		"ct":  {Name: &name, ContentType: &contentType, StringValue: &stringValue},
+	} {
+		var message = senml.Message{Records: []senml.Record{record}}
+		_, err := message.Encode(senml.EXI)
+		unsupportedFieldError, ok := err.(*senml.UnsupportedEXIFieldError)
+		if !ok {
+			t.Error("Encoding a content type as EXI should result in an UnsupportedEXIFieldError, got: ", err)
+			continue
+		}
+		if unsupportedFieldError.Label != expectedLabel {
+			t.Errorf("The error contains an unexpected label: %v", unsupportedFieldError)
+		}
+	}
+}
+
+func TestDecodePayload(t *testing.T) {
+	var jsonContentFormat = "50"
+	var jsonContentType = "application/vnd.example+json; charset=utf-8"
+	var cborContentFormat = "60"
+	var textContentFormat = "0"
+	var jsonString = `{"errors":2}`
+	var text = "restarted"
+	var cborData, _ = hex.DecodeString("a1666572726f727302")
+
+	var tests = []struct {
+		name     string
+		record   senml.Record
+		expected interface{}
+	}{
+		{"JSON in vd", senml.Record{ContentType: &jsonContentFormat}, map[string]interface{}{"errors": float64(2)}},
+		{"JSON in vs", senml.Record{ContentType: &jsonContentType, StringValue: &jsonString}, map[string]interface{}{"errors": float64(2)}},
+		{"CBOR in vd", senml.Record{ContentType: &cborContentFormat}, map[string]interface{}{"errors": int64(2)}},
+		{"text in vs", senml.Record{ContentType: &textContentFormat, StringValue: &text}, text},
+		{"vs without content type", senml.Record{StringValue: &text}, text},
+		{"vd without content type", senml.Record{}, []byte(jsonString)},
+	}
+	tests[0].record.SetDataValueBytes([]byte(jsonString))
+	tests[2].record.SetDataValueBytes(cborData)
+	tests[5].record.SetDataValueBytes([]byte(jsonString))
+
+	for _, test := range tests {
+		payload, err := test.record.DecodePayload()
+		if err != nil {
+			t.Errorf("Decoding the payload (%v) failed: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(payload, test.expected) {
+			t.Errorf("Decoding the payload (%v) resulted in %#v", test.name, payload)
+		}
+	}
+}
+
+func TestDecodePayloadInvalid(t *testing.T) {
+	var xmlContentType = "application/xml"
+	var jsonContentFormat = "50"
+	var text = "restarted"
+
+	_, err := senml.Record{ContentType: &xmlContentType, StringValue: &text}.DecodePayload()
+	if _, ok := err.(*senml.UnsupportedContentTypeError); !ok {
+		t.Error("Decoding a payload with an unsupported content type should result in an UnsupportedContentTypeError, got: ", err)
+	}
+	_, err = senml.Record{ContentType: &jsonContentFormat, StringValue: &text}.DecodePayload()
+	if err == nil {
+		t.Error("Decoding an invalid JSON payload should result in an error")
+	}
+	_, err = senml.Record{ContentType: &jsonContentFormat}.DecodePayload()
+	if _, ok := err.(*senml.MissingValueError); !ok {
+		t.Error("Decoding the payload of a record without a string or binary value should result in a MissingValueError, got: ", err)
+	}
+}
+
+func TestUnsupportedContentTypeError(t *testing.T) {
+	err := &senml.UnsupportedContentTypeError{
+		ContentType: "application/xml",
+	}
+	message := err.Error()
+	if !strings.Contains(message, "application/xml") {
+		t.Error("The error message does not contain the content type.")
+	}
+}
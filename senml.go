@@ -2,15 +2,21 @@
 package senml
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"regexp"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
-// SupportedVersion declares the maximum version of the SenML format supported by this library
+// SupportedVersion declares the version of the SenML format defined in RFC 8428. Higher version numbers contain the features used by the message (see Features).
 const SupportedVersion int = 10
 
 // EncodingFormat declares the supported encoding formats of the SenML message
@@ -22,8 +28,39 @@ const (
 
 	// XML will use encoding/xml to serialize/deserialize the message
 	XML
+
+	// CBOR will use the CBOR representation with integer labels as defined in RFC 8428 chapter 6 to serialize/deserialize the message
+	CBOR
+
+	// EXI will use the schema-informed EXI representation in strict mode as defined in RFC 8428 chapter 8 to serialize/deserialize the message
+	EXI
+
+	// SenSMLJSON is the JSON representation of a SenSML stream. Every record is written on its own line.
+	SenSMLJSON
+
+	// SenSMLXML is the XML representation of a SenSML stream, which is the same as the XML representation of a SenML pack
+	SenSMLXML
+
+	// SenSMLCBOR is the CBOR representation of a SenSML stream, which is the same as the CBOR representation of a SenML pack
+	SenSMLCBOR
+
+	// SenSMLEXI is the EXI representation of a SenSML stream, which is the same as the EXI representation of a SenML pack
+	SenSMLEXI
 )
 
+// IsStream returns true if the format is the representation of a SenSML stream, whose records are kept in their order (RFC 8428 chapter 4.8).
+func (format EncodingFormat) IsStream() bool {
+	return format >= SenSMLJSON && format <= SenSMLEXI
+}
+
+// packFormat returns the SenML format which has the same representation as the format
+func (format EncodingFormat) packFormat() EncodingFormat {
+	if format.IsStream() {
+		return format - SenSMLJSON
+	}
+	return format
+}
+
 // Message is used to serialize and deserialize a SenML message
 type Message struct {
 	/*
@@ -35,6 +72,12 @@ type Message struct {
 		Records of the message
 	*/
 	Records []Record `xml:"senml"`
+
+	/*
+		The message is a SenSML stream. The records are kept in their order while resolving.
+		Set by Decode if a SenSML format is used.
+	*/
+	Stream bool `json:"-" xml:"-"`
 }
 
 // Record is a single record inside a SenML message
@@ -65,18 +108,25 @@ type Record struct {
 	/*
 		A base value is added to the value found in an entry, similar to Base Time.
 	*/
-	BaseValue *float64 `json:"bv:omitempty" xml:"bv,attr,omitempty"`
+	BaseValue *float64 `json:"bv,omitempty" xml:"bv,attr,omitempty"`
 
 	/*
 		A base sum is added to the sum found in an entry, similar to Base Time.
 	*/
-	BaseSum *float64 `json:"bs:omitempty" xml:"bs,attr,omitempty"`
+	BaseSum *float64 `json:"bs,omitempty" xml:"bs,attr,omitempty"`
 
 	/*
 		Version number of the media type format. This field is an optional positive integer and defaults to 10 if not present.
 	*/
 	BaseVersion *int `json:"bver,omitempty" xml:"bver,attr,omitempty"`
 
+	/*
+		A base content type that is assumed for all entries, unless
+		otherwise indicated (RFC 9193). EXI doesn't support this field,
+		encoding it as EXI results in an UnsupportedEXIFieldError.
+	*/
+	BaseContentType *string `json:"bct,omitempty" xml:"bct,attr,omitempty"`
+
 	/*
 		Name of the sensor or parameter. When appended to the Base
 		Name field, this must result in a globally unique identifier for
@@ -126,6 +176,24 @@ type Record struct {
 		sensors or the communications path from the sensor.
 	*/
 	UpdateTime *float64 `json:"ut,omitempty" xml:"ut,attr,omitempty"`
+
+	/*
+		Content type of the StringValue or DataValue (RFC 9193). Either
+		a CoAP Content-Format ID like "50" or a content type like
+		"application/json". Optional. EXI doesn't support this field,
+		encoding it as EXI results in an UnsupportedEXIFieldError.
+	*/
+	ContentType *string `json:"ct,omitempty" xml:"ct,attr,omitempty"`
+
+	/*
+		Fields with labels which are not known to this library (RFC 8428 chapter 4.4).
+		They are kept while decoding and written back while encoding.
+		Values of JSON fields have the types of encoding/json, XML attributes are strings
+		and CBOR fields use their decimal label as key. EXI doesn't support extensions,
+		encoding a record with extensions as EXI results in an UnsupportedEXIFieldError.
+		Labels ending with "_" must be understood, Resolve fails if such a label is present.
+	*/
+	Extensions map[string]interface{} `json:"-" xml:"-"`
 }
 
 // InvalidNameErrorReason declares the reason the name is invalid
@@ -167,26 +235,6 @@ func newInvalidNameError(reason InvalidNameErrorReason) *InvalidNameError {
 	}
 }
 
-// UnsupportedVersionError is an error which is returned when the message has an unsupported SenML version
-type UnsupportedVersionError struct {
-	// The maximum version currently supported by this library
-	SupportedVersion int
-
-	// The version of the given message
-	GivenVersion int
-}
-
-func (err *UnsupportedVersionError) Error() string {
-	return fmt.Sprintf("The version of the message is unsupported. (maximum supported version: %v, got: %v)", err.SupportedVersion, err.GivenVersion)
-}
-
-func newUnsupportedVersionError(givenVersion int) *UnsupportedVersionError {
-	return &UnsupportedVersionError{
-		SupportedVersion: SupportedVersion,
-		GivenVersion:     givenVersion,
-	}
-}
-
 // DifferentVersionError is an error which is returned when at least one record has a different BaseVersion than the others. This is not allowed since all records must have the same version number (RFC 8428 chapter 4.4).
 type DifferentVersionError struct {
 	// The version currently used to parse the records. This could have been set by a preceding record or it defaults to the supported version if no BaseVersion field was set on a preceding record.
@@ -219,6 +267,22 @@ func newMissingValueError() *MissingValueError {
 	return &MissingValueError{}
 }
 
+// InvalidDataValueError is an error which is returned when the DataValue of a record is not encoded as base64url without padding (RFC 8428 chapter 4.3).
+type InvalidDataValueError struct {
+	// The invalid data value
+	DataValue string
+}
+
+func (err *InvalidDataValueError) Error() string {
+	return fmt.Sprintf("The data value %q is not encoded as base64url without padding", err.DataValue)
+}
+
+func newInvalidDataValueError(dataValue string) *InvalidDataValueError {
+	return &InvalidDataValueError{
+		DataValue: dataValue,
+	}
+}
+
 // UnsupportedFormatError is an error which is returned when an unsupported encoding/decoding format was given.
 type UnsupportedFormatError struct {
 	// The given format for encoding/decoding
@@ -239,104 +303,487 @@ func newUnsupportedFormatError(format EncodingFormat) *UnsupportedFormatError {
 // Returns a non-resolved message, you need to resolve it using Resolve() to get
 // base attributes resolution, absolute time, etc.
 func Decode(encodedMessage []byte, format EncodingFormat) (message Message, err error) {
-	switch format {
+	switch format.packFormat() {
 	case JSON:
 		err = json.Unmarshal(encodedMessage, &message.Records)
 	case XML:
 		err = xml.Unmarshal(encodedMessage, &message)
+	case CBOR:
+		message, err = decodeCBOR(encodedMessage)
+	case EXI:
+		message, err = decodeEXI(encodedMessage)
 	default:
 		err = newUnsupportedFormatError(format)
 	}
+	if err == nil {
+		message.Stream = format.IsStream()
+	}
 	return
 }
 
 // Encode encodes the message with the given encoding format.
 func (message Message) Encode(format EncodingFormat) ([]byte, error) {
-	switch format {
+	if format == SenSMLJSON {
+		return encodeStreamJSON(message)
+	}
+	switch format.packFormat() {
 	case JSON:
 		return json.Marshal(message.Records)
 	case XML:
 		return xml.Marshal(message)
+	case CBOR:
+		return encodeCBOR(message)
+	case EXI:
+		return encodeEXI(message)
 	default:
 		return nil, newUnsupportedFormatError(format)
 	}
 }
 
+// encodeStreamJSON encodes the records as a JSON array with every record on its own line, so the stream can be processed line by line
+func encodeStreamJSON(message Message) ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteByte('[')
+	for i, record := range message.Records {
+		encodedRecord, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		buffer.WriteByte('\n')
+		buffer.Write(encodedRecord)
+	}
+	buffer.WriteString("\n]")
+	return buffer.Bytes(), nil
+}
+
+// DataValueBytes returns the binary data of the DataValue field, which is decoded from base64url without padding.
+// Returns nil if the DataValue field is not set.
+func (record Record) DataValueBytes() ([]byte, error) {
+	if record.DataValue == nil {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(*record.DataValue)
+	if err != nil {
+		return nil, newInvalidDataValueError(*record.DataValue)
+	}
+	return data, nil
+}
+
+// SetDataValueBytes sets the DataValue field to the base64url encoding without padding of the binary data.
+func (record *Record) SetDataValueBytes(data []byte) {
+	var dataValue = base64.RawURLEncoding.EncodeToString(data)
+	record.DataValue = &dataValue
+}
+
+// ResolveOptions control how records are resolved. The zero value resolves records like Resolve.
+type ResolveOptions struct {
+	// RejectUnknownUnits results in an UnknownUnitError if the resolved unit of a record is not registered (see LookupUnit).
+	RejectUnknownUnits bool
+
+	// ReferenceTime is the time which relative times are resolved against, e.g. the time an archived message was received.
+	// If it is the zero time, the time returned by Clock is used.
+	ReferenceTime time.Time
+
+	// Clock returns the current time which relative times are resolved against if no ReferenceTime is set. If nil, time.Now is used.
+	Clock func() time.Time
+
+	// KeepOrder keeps the records in the order of the message instead of sorting them chronologically. The records of SenSML streams are always kept in their order.
+	KeepOrder bool
+
+	// NameValidation declares how strictly the resolved names are validated.
+	NameValidation NameValidation
+
+	// SkipVersionStamping doesn't set the BaseVersion field on the resolved records, even if the version of the message differs from SupportedVersion.
+	SkipVersionStamping bool
+}
+
+// NameValidation declares how strictly the resolved names of the records are validated
+type NameValidation int
+
+const (
+	// StrictNameValidation only allows names which conform to RFC 8428 chapter 4.5.1
+	StrictNameValidation NameValidation = iota
+
+	// RelaxedNameValidation allows all non-empty names which don't contain control characters, e.g. names of legacy devices which contain "#" or spaces
+	RelaxedNameValidation
+
+	// NoNameValidation doesn't validate the names at all, the resolved name may be empty
+	NoNameValidation
+)
+
+// now returns the time which relative times are resolved against in seconds since the Unix epoch
+func (options ResolveOptions) now() float64 {
+	var now time.Time
+	if !options.ReferenceTime.IsZero() {
+		now = options.ReferenceTime
+	} else if options.Clock != nil {
+		now = options.Clock()
+	} else {
+		now = time.Now()
+	}
+	return float64(now.UnixNano()) / float64(time.Second)
+}
+
 // Resolve adds the base attributes to the normal attributes, calculates absolute time from relative time etc.
+// The records are sorted chronologically, unless the message is a SenSML stream.
 func (message Message) Resolve() (resolvedMessage Message, err error) {
-	var timeNow = float64(time.Now().Unix())
+	return message.ResolveWithOptions(ResolveOptions{})
+}
 
-	var baseName *string
-	var baseTime *float64
-	var baseUnit *string
-	var baseValue *float64
-	var baseSum *float64
-	var baseVersion *int
+// ResolveWithOptions resolves the message like Resolve using the given options.
+func (message Message) ResolveWithOptions(options ResolveOptions) (resolvedMessage Message, err error) {
+	var timeNow = options.now()
+	var resolver = &Resolver{
+		options: options,
+		timeNow: &timeNow,
+	}
+	resolvedMessage.Stream = message.Stream
 
 	for _, record := range message.Records {
-		var resolvedRecord = Record{}
-
-		if record.BaseVersion != nil {
-			if *record.BaseVersion > SupportedVersion {
-				err = newUnsupportedVersionError(*record.BaseVersion)
-				return
-			} else if baseVersion == nil {
-				baseVersion = record.BaseVersion
-			} else if *record.BaseVersion != *baseVersion {
-				err = newDifferentVersionError(*baseVersion, *record.BaseVersion)
-				return
-			}
-		} else if baseVersion == nil {
-			var defaultVersion = SupportedVersion
-			baseVersion = &defaultVersion
-		}
-		if record.BaseName != nil {
-			baseName = record.BaseName
-		}
-		if record.BaseTime != nil {
-			baseTime = record.BaseTime
-		}
-		if record.BaseUnit != nil {
-			baseUnit = record.BaseUnit
-		}
-		if record.BaseValue != nil {
-			baseValue = record.BaseValue
+		var resolvedRecord Record
+		resolvedRecord, err = resolver.Resolve(record)
+		if err != nil {
+			return
 		}
-		if record.BaseSum != nil {
-			baseSum = record.BaseSum
+		if record.HasOnlyBaseFields() {
+			continue
 		}
+		resolvedMessage.Records = append(resolvedMessage.Records, resolvedRecord)
+	}
+
+	if !options.KeepOrder && !message.Stream {
+		sortRecordsChronologically(resolvedMessage.Records)
+	}
+	return
+}
+
+// Resolver resolves records one at a time. The base attributes of the preceding records are kept across calls to Resolve, which allows resolving SenSML streams that never end.
+type Resolver struct {
+	options ResolveOptions
+
+	// the time which relative times are resolved against. If nil, the time of the options at the moment of resolving a record is used.
+	timeNow *float64
+
+	baseName        *string
+	baseTime        *float64
+	baseUnit        *string
+	baseValue       *float64
+	baseSum         *float64
+	baseVersion     *int
+	baseContentType *string
+
+	// the base variants of registered labels by the name of the label
+	baseExtensions map[string]interface{}
 
-		var resolveNameError *InvalidNameError
-		resolvedRecord.Name, resolveNameError = resolveName(baseName, record.Name)
-		if resolveNameError != nil {
-			err = resolveNameError
+	// allows records without a value, which are used in FETCH and iPATCH documents
+	allowMissingValue bool
+}
+
+// NewResolver returns a new resolver without any base attributes.
+func NewResolver() *Resolver {
+	return &Resolver{}
+}
+
+// NewResolverWithOptions returns a new resolver without any base attributes which uses the given options.
+func NewResolverWithOptions(options ResolveOptions) *Resolver {
+	return &Resolver{
+		options: options,
+	}
+}
+
+// Resolve adds the base attributes of this and all preceding records to the normal attributes of the record, calculates absolute time from relative time etc.
+// Records are not sorted, they should be processed in the order they were resolved.
+// A record which only contains base fields (see HasOnlyBaseFields) only sets the base attributes for the following records and resolves to an empty record, which should be skipped.
+func (resolver *Resolver) Resolve(record Record) (resolvedRecord Record, err error) {
+	var timeNow float64
+	if resolver.timeNow != nil {
+		timeNow = *resolver.timeNow
+	} else {
+		timeNow = resolver.options.now()
+	}
+
+	if record.BaseVersion != nil {
+		if unsupportedFeaturesError := validateVersion(*record.BaseVersion); unsupportedFeaturesError != nil {
+			err = unsupportedFeaturesError
+			return
+		} else if resolver.baseVersion == nil {
+			var baseVersion = *record.BaseVersion
+			resolver.baseVersion = &baseVersion
+		} else if *record.BaseVersion != *resolver.baseVersion {
+			err = newDifferentVersionError(*resolver.baseVersion, *record.BaseVersion)
 			return
 		}
-		resolvedRecord.Unit = resolveUnit(baseUnit, record.Unit)
-		resolvedRecord.Value = resolveValue(baseValue, record.Value)
-		resolvedRecord.BoolValue = resolveBoolValue(record.BoolValue)
-		resolvedRecord.StringValue = resolveStringValue(record.StringValue)
-		resolvedRecord.DataValue = resolveDataValue(record.DataValue)
-		resolvedRecord.Sum = resolveSum(baseSum, record.Sum)
-		resolvedRecord.Time = resolveTime(baseTime, record.Time, timeNow)
-		resolvedRecord.UpdateTime = resolveUpdateTime(record.UpdateTime)
+	} else if resolver.baseVersion == nil {
+		var defaultVersion = SupportedVersion
+		resolver.baseVersion = &defaultVersion
+	}
+	if record.BaseName != nil {
+		resolver.baseName = record.BaseName
+	}
+	if record.BaseTime != nil {
+		resolver.baseTime = record.BaseTime
+	}
+	if record.BaseUnit != nil {
+		resolver.baseUnit = record.BaseUnit
+	}
+	if record.BaseValue != nil {
+		resolver.baseValue = record.BaseValue
+	}
+	if record.BaseSum != nil {
+		resolver.baseSum = record.BaseSum
+	}
+	if record.BaseContentType != nil {
+		resolver.baseContentType = record.BaseContentType
+	}
+	if record.HasOnlyBaseFields() {
+		return
+	}
 
+	var resolveNameError *InvalidNameError
+	resolvedRecord.Name, resolveNameError = resolveName(resolver.baseName, record.Name, resolver.options.NameValidation)
+	if resolveNameError != nil {
+		err = resolveNameError
+		return
+	}
+	resolvedRecord.Unit = resolveUnit(resolver.baseUnit, record.Unit)
+	resolvedRecord.Value = resolveValue(resolver.baseValue, record.Value)
+	resolvedRecord.BoolValue = resolveBoolValue(record.BoolValue)
+	resolvedRecord.StringValue = resolveStringValue(record.StringValue)
+	resolvedRecord.DataValue = resolveDataValue(record.DataValue)
+	resolvedRecord.Sum = resolveSum(resolver.baseSum, record.Sum)
+	resolvedRecord.Time = resolveTime(resolver.baseTime, record.Time, timeNow)
+	resolvedRecord.UpdateTime = resolveUpdateTime(record.UpdateTime)
+	resolvedRecord.ContentType = resolveContentType(resolver.baseContentType, record.ContentType)
+	if resolver.baseExtensions == nil {
+		resolver.baseExtensions = map[string]interface{}{}
+	}
+	resolvedRecord.Extensions = resolveExtensions(resolver.baseExtensions, record.Extensions)
+
+	var mustUnderstandError *MustUnderstandError
+	mustUnderstandError = validateExtensions(record)
+	if mustUnderstandError != nil {
+		err = mustUnderstandError
+		return
+	}
+
+	if !resolver.allowMissingValue {
 		var resolveValueError *MissingValueError
 		resolveValueError = validateRecordHasValue(resolvedRecord)
 		if resolveValueError != nil {
 			err = resolveValueError
 			return
 		}
+	}
 
-		resolvedMessage.Records = append(resolvedMessage.Records, resolvedRecord)
+	var dataValueError *InvalidDataValueError
+	dataValueError = validateDataValue(resolvedRecord)
+	if dataValueError != nil {
+		err = dataValueError
+		return
 	}
 
-	setBaseVersionIfNecessary(resolvedMessage, baseVersion)
-	sortRecordsChronologically(resolvedMessage.Records)
+	if resolver.options.RejectUnknownUnits {
+		var unknownUnitError *UnknownUnitError
+		unknownUnitError = validateUnit(resolvedRecord)
+		if unknownUnitError != nil {
+			err = unknownUnitError
+			return
+		}
+	}
+
+	if !resolver.options.SkipVersionStamping {
+		setBaseVersionIfNecessary(&resolvedRecord, resolver.baseVersion)
+	}
+	return
+}
+
+// ResolveRecords resolves the message like Resolve and converts the resolved records to the typed ResolvedRecord representation.
+func (message Message) ResolveRecords() (resolvedRecords []ResolvedRecord, err error) {
+	resolvedMessage, err := message.Resolve()
+	if err != nil {
+		return
+	}
+	for _, record := range resolvedMessage.Records {
+		var resolvedRecord ResolvedRecord
+		resolvedRecord, err = newResolvedRecord(record)
+		if err != nil {
+			return
+		}
+		resolvedRecords = append(resolvedRecords, resolvedRecord)
+	}
 	return
 }
 
-func resolveName(baseName *string, name *string) (*string, *InvalidNameError) {
+// ResolveRecord resolves the record like Resolve and converts it to the typed ResolvedRecord representation.
+func (resolver *Resolver) ResolveRecord(record Record) (ResolvedRecord, error) {
+	resolvedRecord, err := resolver.Resolve(record)
+	if err != nil {
+		return ResolvedRecord{}, err
+	}
+	return newResolvedRecord(resolvedRecord)
+}
+
+// ValueKind declares which of the value fields is set on a Value
+type ValueKind int
+
+const (
+	// NoValue means that no value field is set, which is only allowed if the record has a sum
+	NoValue ValueKind = iota
+
+	// FloatValue means that the value is a floating-point number ("v" field)
+	FloatValue
+
+	// BoolValue means that the value is a boolean ("vb" field)
+	BoolValue
+
+	// StringValue means that the value is a string ("vs" field)
+	StringValue
+
+	// DataValue means that the value is binary data ("vd" field)
+	DataValue
+)
+
+// Value is the value of a resolved record. It holds exactly one of a floating-point number, a boolean, a string or binary data.
+type Value struct {
+	kind        ValueKind
+	floatValue  float64
+	boolValue   bool
+	stringValue string
+	dataValue   []byte
+}
+
+// NewFloatValue returns a Value which holds the floating-point number
+func NewFloatValue(value float64) Value {
+	return Value{kind: FloatValue, floatValue: value}
+}
+
+// NewBoolValue returns a Value which holds the boolean
+func NewBoolValue(value bool) Value {
+	return Value{kind: BoolValue, boolValue: value}
+}
+
+// NewStringValue returns a Value which holds the string
+func NewStringValue(value string) Value {
+	return Value{kind: StringValue, stringValue: value}
+}
+
+// NewDataValue returns a Value which holds the binary data
+func NewDataValue(value []byte) Value {
+	return Value{kind: DataValue, dataValue: value}
+}
+
+// Kind returns which kind of value is held
+func (value Value) Kind() ValueKind {
+	return value.kind
+}
+
+// AsFloat returns the floating-point number and true if the value holds one
+func (value Value) AsFloat() (float64, bool) {
+	return value.floatValue, value.kind == FloatValue
+}
+
+// AsBool returns the boolean and true if the value holds one
+func (value Value) AsBool() (bool, bool) {
+	return value.boolValue, value.kind == BoolValue
+}
+
+// AsString returns the string and true if the value holds one
+func (value Value) AsString() (string, bool) {
+	return value.stringValue, value.kind == StringValue
+}
+
+// AsData returns the binary data and true if the value holds binary data
+func (value Value) AsData() ([]byte, bool) {
+	return value.dataValue, value.kind == DataValue
+}
+
+// ResolvedRecord is a typed representation of a resolved record
+type ResolvedRecord struct {
+	// The resolved name of the sensor or parameter
+	Name string
+
+	// The resolved unit of the value. Empty if the record has no unit.
+	Unit string
+
+	// The value of the record. Binary data is already decoded from base64url.
+	Value Value
+
+	// The resolved sum. Only valid if HasSum is true.
+	Sum    float64
+	HasSum bool
+
+	// The absolute time when the value was recorded. The zero time if the record has no time.
+	Time time.Time
+
+	// The maximum time before the sensor provides an updated reading. Zero if the record has no update time.
+	UpdateTime time.Duration
+
+	// The resolved content type of the string or binary value. Empty if the record has no content type.
+	ContentType string
+}
+
+func newResolvedRecord(record Record) (resolvedRecord ResolvedRecord, err error) {
+	if record.Name != nil {
+		resolvedRecord.Name = *record.Name
+	}
+	if record.Unit != nil {
+		resolvedRecord.Unit = *record.Unit
+	}
+	switch {
+	case record.Value != nil:
+		resolvedRecord.Value = NewFloatValue(*record.Value)
+	case record.BoolValue != nil:
+		resolvedRecord.Value = NewBoolValue(*record.BoolValue)
+	case record.StringValue != nil:
+		resolvedRecord.Value = NewStringValue(*record.StringValue)
+	case record.DataValue != nil:
+		var data []byte
+		data, err = record.DataValueBytes()
+		if err != nil {
+			return
+		}
+		resolvedRecord.Value = NewDataValue(data)
+	}
+	if record.Sum != nil {
+		resolvedRecord.Sum = *record.Sum
+		resolvedRecord.HasSum = true
+	}
+	if record.Time != nil {
+		resolvedRecord.Time = secondsToTime(*record.Time)
+	}
+	if record.UpdateTime != nil {
+		resolvedRecord.UpdateTime = time.Duration(*record.UpdateTime * float64(time.Second))
+	}
+	if record.ContentType != nil {
+		resolvedRecord.ContentType = *record.ContentType
+	}
+	return
+}
+
+// secondsToTime converts seconds since the UNIX epoch to a time.Time in UTC.
+// The shortest decimal representation of the seconds is used, so that e.g. 1.276020076001e+09 results in exactly one millisecond.
+func secondsToTime(seconds float64) time.Time {
+	var formatted = strconv.FormatFloat(seconds, 'f', -1, 64)
+	var integral, fractional = formatted, ""
+	if separator := strings.IndexByte(formatted, '.'); separator >= 0 {
+		integral, fractional = formatted[:separator], formatted[separator+1:]
+	}
+	if len(fractional) > 9 {
+		fractional = fractional[:9]
+	}
+	fractional += strings.Repeat("0", 9-len(fractional))
+	integralSeconds, _ := strconv.ParseInt(integral, 10, 64)
+	nanoseconds, _ := strconv.ParseInt(fractional, 10, 64)
+	if strings.HasPrefix(formatted, "-") {
+		nanoseconds = -nanoseconds
+	}
+	return time.Unix(integralSeconds, nanoseconds).UTC()
+}
+
+func resolveName(baseName *string, name *string, validation NameValidation) (*string, *InvalidNameError) {
 	var resolvedName string
 	if baseName != nil {
 		resolvedName = *baseName
@@ -344,9 +791,23 @@ func resolveName(baseName *string, name *string) (*string, *InvalidNameError) {
 	if name != nil {
 		resolvedName += *name
 	}
+	if validation == NoNameValidation {
+		if baseName == nil && name == nil {
+			return nil, nil
+		}
+		return &resolvedName, nil
+	}
 	if len(resolvedName) == 0 {
 		return nil, newInvalidNameError(Empty)
 	}
+	if validation == RelaxedNameValidation {
+		for _, character := range resolvedName {
+			if unicode.IsControl(character) || character == utf8.RuneError {
+				return nil, newInvalidNameError(ContainsInvalidCharacter)
+			}
+		}
+		return &resolvedName, nil
+	}
 	validFirstCharacterExp := regexp.MustCompile(`^[a-zA-Z0-9]*$`)
 	if !validFirstCharacterExp.MatchString(resolvedName[:1]) {
 		return nil, newInvalidNameError(FirstCharacterInvalid)
@@ -369,6 +830,17 @@ func resolveUnit(baseUnit *string, unit *string) *string {
 	return nil
 }
 
+func resolveContentType(baseContentType *string, contentType *string) *string {
+	if contentType != nil {
+		var resolvedContentType = *contentType
+		return &resolvedContentType
+	} else if baseContentType != nil {
+		var resolvedContentType = *baseContentType
+		return &resolvedContentType
+	}
+	return nil
+}
+
 func resolveValue(baseValue *float64, value *float64) *float64 {
 	var resolvedValue float64
 	if baseValue != nil {
@@ -421,6 +893,9 @@ func resolveSum(baseSum *float64, sum *float64) *float64 {
 	return nil
 }
 
+// relativeTimeThreshold is 2^28 seconds. Resolved times below it are relative to the current time, all other times are absolute (RFC 8428 chapter 4.5.3).
+const relativeTimeThreshold float64 = 1 << 28
+
 func resolveTime(baseTime *float64, time *float64, timeNow float64) *float64 {
 	var resolvedTime float64
 	if baseTime != nil {
@@ -430,7 +905,7 @@ func resolveTime(baseTime *float64, time *float64, timeNow float64) *float64 {
 		resolvedTime += *time
 	}
 	if baseTime != nil || time != nil {
-		if resolvedTime < 2^28 {
+		if resolvedTime < relativeTimeThreshold {
 			resolvedTime += timeNow
 		}
 		return &resolvedTime
@@ -446,6 +921,16 @@ func resolveUpdateTime(updateTime *float64) *float64 {
 	return nil
 }
 
+// HasOnlyBaseFields returns true if the record contains base fields but no other fields, e.g. {"bn":"urn:dev:ow:10e2073a01080063:"}.
+// Such a record only sets the base fields for the following records and doesn't resolve to a record on its own (RFC 8428 chapter 5.1.7).
+// A record with a base value or a base sum is not counted, since it resolves to a record with that value or sum.
+func (record Record) HasOnlyBaseFields() bool {
+	var hasBaseField = record.BaseName != nil || record.BaseTime != nil || record.BaseUnit != nil || record.BaseVersion != nil || record.BaseContentType != nil
+	var hasOtherField = record.BaseValue != nil || record.BaseSum != nil || record.Name != nil || record.Unit != nil || record.Value != nil || record.StringValue != nil || record.BoolValue != nil || record.DataValue != nil || record.Sum != nil ||
+		record.Time != nil || record.UpdateTime != nil || record.ContentType != nil || len(record.Extensions) > 0
+	return hasBaseField && !hasOtherField
+}
+
 func validateRecordHasValue(record Record) *MissingValueError {
 	if record.Value == nil && record.StringValue == nil && record.BoolValue == nil && record.DataValue == nil && record.Sum == nil {
 		return newMissingValueError()
@@ -453,13 +938,20 @@ func validateRecordHasValue(record Record) *MissingValueError {
 	return nil
 }
 
-func setBaseVersionIfNecessary(message Message, baseVersion *int) {
-	if baseVersion != nil && *baseVersion < SupportedVersion {
-		for i := range message.Records {
-			var resolvedVersion = *baseVersion
-			message.Records[i].BaseVersion = &resolvedVersion
+func validateDataValue(record Record) *InvalidDataValueError {
+	if record.DataValue != nil {
+		if _, err := base64.RawURLEncoding.DecodeString(*record.DataValue); err != nil {
+			return newInvalidDataValueError(*record.DataValue)
 		}
 	}
+	return nil
+}
+
+func setBaseVersionIfNecessary(record *Record, baseVersion *int) {
+	if baseVersion != nil && *baseVersion != SupportedVersion {
+		var resolvedVersion = *baseVersion
+		record.BaseVersion = &resolvedVersion
+	}
 }
 
 func sortRecordsChronologically(records []Record) {
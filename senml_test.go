@@ -1,7 +1,10 @@
 package senml_test
 
 import (
+	"io"
+	"strings"
 	"testing"
+	"time"
 
 	senml "github.com/nkristek/go-senml"
 )
@@ -103,6 +106,7 @@ func TestEncodeInvalidFormat(t *testing.T) {
 }
 
 func TestResolveUnsupportedSenMLVersion(t *testing.T) {
+	// 11 sets the feature code 0, which is reserved by RFC 9100
 	var unsupportedVersion = 11
 	var name = "test"
 	var value float64 = 1
@@ -117,10 +121,14 @@ func TestResolveUnsupportedSenMLVersion(t *testing.T) {
 	}
 
 	_, err := message.Resolve()
-	if err == nil {
-		t.Error("Resolving an unsupported SenML version should result in an error")
+	unsupportedFeaturesError, ok := err.(*senml.UnsupportedFeaturesError)
+	if !ok {
+		t.Error("Resolving an unsupported SenML version should result in an UnsupportedFeaturesError, got: ", err)
 		return
 	}
+	if unsupportedFeaturesError.UnsupportedFeatures != 1 {
+		t.Errorf("The error contains unexpected features: %v", unsupportedFeaturesError.UnsupportedFeatures)
+	}
 }
 
 func TestResolveBaseVersionIsSetIfLowerThanMaximumSupported(t *testing.T) {
@@ -256,6 +264,43 @@ func TestResolveNoValue(t *testing.T) {
 	}
 }
 
+func TestResolveBaseOnlyRecord(t *testing.T) {
+	var baseName = "urn:dev:ow:10e2073a01080063:"
+	var name = "temp"
+	var value float64 = 23.1
+	message := senml.Message{
+		Records: []senml.Record{
+			{
+				BaseName: &baseName,
+			},
+			{
+				Name:  &name,
+				Value: &value,
+			},
+		},
+	}
+	if !message.Records[0].HasOnlyBaseFields() || message.Records[1].HasOnlyBaseFields() {
+		t.Error("Only the first record should contain only base fields")
+	}
+	if err := message.Validate(); err != nil {
+		t.Error("A record with only base fields should be valid: ", err)
+		return
+	}
+
+	resolvedMessage, err := message.Resolve()
+	if err != nil {
+		t.Error("Resolving a record with only base fields failed: ", err)
+		return
+	}
+	if len(resolvedMessage.Records) != 1 {
+		t.Errorf("The record with only base fields should not be resolved to a record, got %v records", len(resolvedMessage.Records))
+		return
+	}
+	if *resolvedMessage.Records[0].Name != baseName+name {
+		t.Error("The base name of the record with only base fields was not applied")
+	}
+}
+
 func TestResolveValue(t *testing.T) {
 	var name = "test"
 	var value float64 = 1
@@ -372,6 +417,67 @@ func TestResolveDataValue(t *testing.T) {
 	}
 }
 
+func TestResolveInvalidDataValue(t *testing.T) {
+	var name = "test"
+	for _, dataValue := range []string{"not base64url!", "aGkgCg==", "aGkgCg/+"} {
+		var dataValue = dataValue
+		message := senml.Message{
+			Records: []senml.Record{
+				{
+					Name:      &name,
+					DataValue: &dataValue,
+				},
+			},
+		}
+
+		_, err := message.Resolve()
+		if err == nil {
+			t.Errorf("Resolving a record with the invalid data value %q should result in an error", dataValue)
+			continue
+		}
+
+		invalidDataValueError, ok := err.(*senml.InvalidDataValueError)
+		if !ok {
+			t.Error("Resolving a record with an invalid data value should result in an InvalidDataValueError, got: ", err)
+			continue
+		}
+		if invalidDataValueError.DataValue != dataValue {
+			t.Error("The error contains an unexpected data value")
+		}
+	}
+}
+
+func TestRecordDataValueBytes(t *testing.T) {
+	var record senml.Record
+	data, err := record.DataValueBytes()
+	if err != nil || data != nil {
+		t.Error("A record without a data value should result in nil data")
+		return
+	}
+
+	var expected = []byte{0x68, 0x69, 0x20, 0x0a, 0xfb, 0xff}
+	record.SetDataValueBytes(expected)
+	if record.DataValue == nil || *record.DataValue != "aGkgCvv_" {
+		t.Error("The data value was not encoded as base64url without padding")
+		return
+	}
+
+	data, err = record.DataValueBytes()
+	if err != nil {
+		t.Error("Decoding the data value failed: ", err)
+		return
+	}
+	if string(data) != string(expected) {
+		t.Error("The decoded data value differs from the encoded data")
+	}
+
+	var invalidDataValue = "aGkgCg=="
+	record.DataValue = &invalidDataValue
+	if _, err := record.DataValueBytes(); err == nil {
+		t.Error("Decoding a padded data value should result in an error")
+	}
+}
+
 func TestResolveSum(t *testing.T) {
 	var name = "test"
 	var sum float64 = 1
@@ -496,7 +602,7 @@ func TestResolveRelativeToAbsoluteTime(t *testing.T) {
 func TestResolveAbsoluteTime(t *testing.T) {
 	var name = "test"
 	var value float64 = 1
-	var time float64 = 2 ^ 28
+	var time float64 = 1 << 28
 	message := senml.Message{
 		Records: []senml.Record{
 			{
@@ -523,6 +629,101 @@ func TestResolveAbsoluteTime(t *testing.T) {
 	}
 }
 
+func TestResolveTimeThreshold(t *testing.T) {
+	var name = "test"
+	var value float64 = 1
+	var referenceTime = time.Unix(1320067464, 500000000)
+	var testCases = []struct {
+		time     float64
+		expected float64
+	}{
+		{0, 1320067464.5},
+		{-60, 1320067404.5},
+		{1000, 1320068464.5},
+		{1<<28 - 1, 1320067464.5 + 1<<28 - 1},
+		{1 << 28, 1 << 28},
+		{1320067464, 1320067464},
+	}
+	for _, testCase := range testCases {
+		var recordTime = testCase.time
+		message := senml.Message{
+			Records: []senml.Record{
+				{
+					Name:  &name,
+					Value: &value,
+					Time:  &recordTime,
+				},
+			},
+		}
+
+		resolvedMessage, err := message.ResolveWithOptions(senml.ResolveOptions{ReferenceTime: referenceTime})
+		if err != nil {
+			t.Error("Resolving the record failed", err)
+			return
+		}
+		if *resolvedMessage.Records[0].Time != testCase.expected {
+			t.Errorf("The time %v was resolved to %v, expected %v", testCase.time, *resolvedMessage.Records[0].Time, testCase.expected)
+		}
+	}
+}
+
+func TestResolveWithClock(t *testing.T) {
+	var name = "test"
+	var value float64 = 1
+	var recordTime float64 = -10
+	var record = senml.Record{
+		Name:  &name,
+		Value: &value,
+		Time:  &recordTime,
+	}
+
+	var now = time.Unix(1000000000, 0)
+	var calls = 0
+	var options = senml.ResolveOptions{
+		Clock: func() time.Time {
+			calls++
+			return now
+		},
+	}
+
+	resolvedMessage, err := senml.Message{Records: []senml.Record{record, record}}.ResolveWithOptions(options)
+	if err != nil {
+		t.Error("Resolving the message failed", err)
+		return
+	}
+	if calls != 1 {
+		t.Error("The clock should be called once per message, got: ", calls)
+	}
+	for _, resolvedRecord := range resolvedMessage.Records {
+		if *resolvedRecord.Time != 999999990 {
+			t.Error("The time was not resolved against the clock, got: ", *resolvedRecord.Time)
+		}
+	}
+
+	var resolver = senml.NewResolverWithOptions(options)
+	for i := 0; i < 2; i++ {
+		now = now.Add(time.Second)
+		resolvedRecord, err := resolver.Resolve(record)
+		if err != nil {
+			t.Error("Resolving the record failed", err)
+			return
+		}
+		if *resolvedRecord.Time != float64(now.Unix())-10 {
+			t.Error("The resolver should use the clock at the moment of resolving, got: ", *resolvedRecord.Time)
+		}
+	}
+
+	options.ReferenceTime = time.Unix(2000000000, 0)
+	resolvedMessage, err = senml.Message{Records: []senml.Record{record}}.ResolveWithOptions(options)
+	if err != nil {
+		t.Error("Resolving the message failed", err)
+		return
+	}
+	if *resolvedMessage.Records[0].Time != 1999999990 {
+		t.Error("The reference time should take precedence over the clock, got: ", *resolvedMessage.Records[0].Time)
+	}
+}
+
 func TestResolveOrderIsChronological(t *testing.T) {
 	var baseName = "test"
 	var value float64 = 1
@@ -598,6 +799,99 @@ func TestResolveOrderIsChronological(t *testing.T) {
 	}
 }
 
+func TestResolveWithOptionsKeepOrder(t *testing.T) {
+	var name = "test"
+	var value float64 = 1
+	var time1 float64 = 1320067464
+	var time2 float64 = 1320067404
+	message := senml.Message{
+		Records: []senml.Record{
+			{Name: &name, Value: &value, Time: &time1},
+			{Name: &name, Value: &value, Time: &time2},
+		},
+	}
+
+	resolvedMessage, err := message.ResolveWithOptions(senml.ResolveOptions{KeepOrder: true})
+	if err != nil {
+		t.Error("Resolving the message failed", err)
+		return
+	}
+	if *resolvedMessage.Records[0].Time != time1 || *resolvedMessage.Records[1].Time != time2 {
+		t.Error("The order of the records was not kept")
+	}
+
+	resolvedMessage, err = message.Resolve()
+	if err != nil {
+		t.Error("Resolving the message failed", err)
+		return
+	}
+	if *resolvedMessage.Records[0].Time != time2 || *resolvedMessage.Records[1].Time != time1 {
+		t.Error("The records should be sorted chronologically by default")
+	}
+}
+
+func TestResolveWithOptionsNameValidation(t *testing.T) {
+	var value float64 = 1
+	var testCases = []struct {
+		name       string
+		validation senml.NameValidation
+		valid      bool
+	}{
+		{"device#1 temperature", senml.StrictNameValidation, false},
+		{"device#1 temperature", senml.RelaxedNameValidation, true},
+		{"device\n1", senml.RelaxedNameValidation, false},
+		{"", senml.RelaxedNameValidation, false},
+		{"device\n1", senml.NoNameValidation, true},
+		{"", senml.NoNameValidation, true},
+	}
+	for _, testCase := range testCases {
+		var name = testCase.name
+		message := senml.Message{
+			Records: []senml.Record{
+				{Name: &name, Value: &value},
+			},
+		}
+
+		resolvedMessage, err := message.ResolveWithOptions(senml.ResolveOptions{NameValidation: testCase.validation})
+		if !testCase.valid {
+			if _, ok := err.(*senml.InvalidNameError); !ok {
+				t.Errorf("Resolving the name %q should result in an InvalidNameError, got: %v", name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Resolving the name %q should not result in an error: %v", name, err)
+			continue
+		}
+		if resolvedMessage.Records[0].Name == nil || *resolvedMessage.Records[0].Name != name {
+			t.Errorf("The name %q was not resolved", name)
+		}
+	}
+}
+
+func TestResolveWithOptionsSkipVersionStamping(t *testing.T) {
+	var name = "test"
+	var value float64 = 1
+	var version = 5
+	message := senml.Message{
+		Records: []senml.Record{
+			{BaseVersion: &version, Name: &name, Value: &value},
+			{Name: &name, Value: &value},
+		},
+	}
+
+	resolvedMessage, err := message.ResolveWithOptions(senml.ResolveOptions{SkipVersionStamping: true})
+	if err != nil {
+		t.Error("Resolving the message failed", err)
+		return
+	}
+	for _, record := range resolvedMessage.Records {
+		if record.BaseVersion != nil {
+			t.Error("The version should not be stamped onto the records")
+		}
+	}
+}
+
 func TestResolveBaseName(t *testing.T) {
 	var baseName = "base/"
 	var name = "test"
@@ -653,7 +947,7 @@ func TestResolveBaseName(t *testing.T) {
 }
 
 func TestResolveBaseTime(t *testing.T) {
-	var baseTime float64 = 2 ^ 28
+	var baseTime float64 = 1 << 28
 	var baseName = "test"
 	var baseValue float64 = 1
 	var time float64 = 1
@@ -868,6 +1162,213 @@ func TestResolveBaseSum(t *testing.T) {
 	}
 }
 
+func TestResolverKeepsBaseAttributesAcrossCalls(t *testing.T) {
+	var baseName = "base/"
+	var baseUnit = "Cel"
+	var baseTime float64 = 1 << 29
+	var name = "test"
+	var value float64 = 1
+	var time float64 = 2
+	var resolver = senml.NewResolver()
+
+	first, err := resolver.Resolve(senml.Record{
+		BaseName: &baseName,
+		BaseUnit: &baseUnit,
+		BaseTime: &baseTime,
+		Name:     &name,
+		Value:    &value,
+	})
+	if err != nil {
+		t.Error("Resolving the first record failed", err)
+		return
+	}
+
+	second, err := resolver.Resolve(senml.Record{
+		Name:  &name,
+		Value: &value,
+		Time:  &time,
+	})
+	if err != nil {
+		t.Error("Resolving the second record failed", err)
+		return
+	}
+
+	for _, record := range []senml.Record{first, second} {
+		if record.Name == nil || *record.Name != baseName+name {
+			t.Error("The base name was not carried across calls")
+		}
+		if record.Unit == nil || *record.Unit != baseUnit {
+			t.Error("The base unit was not carried across calls")
+		}
+		if record.BaseName != nil || record.BaseUnit != nil || record.BaseTime != nil {
+			t.Error("The resolved record has a base attribute set")
+		}
+	}
+	if second.Time == nil || *second.Time != baseTime+time {
+		t.Error("The base time was not carried across calls")
+	}
+}
+
+func TestResolverStream(t *testing.T) {
+	var decoder = senml.NewDecoder(strings.NewReader(xmlData), senml.XML)
+	var resolver = senml.NewResolver()
+	var count = 0
+	for {
+		record, err := decoder.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Error("Decoding the record failed", err)
+			return
+		}
+		resolvedRecord, err := resolver.Resolve(record)
+		if err != nil {
+			t.Error("Resolving the record failed", err)
+			return
+		}
+		if resolvedRecord.BaseVersion == nil || *resolvedRecord.BaseVersion != 5 {
+			t.Error("The BaseVersion attribute is not set on every record if the version is lower than the maximum supported version")
+		}
+		if resolvedRecord.Name == nil || !strings.HasPrefix(*resolvedRecord.Name, "urn:dev:ow:10e2073a0108006:") {
+			t.Error("The base name was not carried across calls")
+		}
+		count++
+	}
+	if count != 7 {
+		t.Error("The stream has an unexpected number of records")
+	}
+}
+
+func TestResolverRecordsHaveDifferentVersion(t *testing.T) {
+	var version = 5
+	var differentVersion = 6
+	var name = "test"
+	var value float64 = 1
+	var resolver = senml.NewResolver()
+
+	_, err := resolver.Resolve(senml.Record{BaseVersion: &version, Name: &name, Value: &value})
+	if err != nil {
+		t.Error("Resolving the first record failed", err)
+		return
+	}
+
+	_, err = resolver.Resolve(senml.Record{BaseVersion: &differentVersion, Name: &name, Value: &value})
+	if _, ok := err.(*senml.DifferentVersionError); !ok {
+		t.Error("Resolving a record with a different version than a preceding record should result in a DifferentVersionError")
+	}
+}
+
+func TestResolveRecords(t *testing.T) {
+	const data = `[
+		{"bn":"urn:dev:ow:10e2073a01080063:","bt":1.276020076001e+09,"n":"temp","u":"Cel","v":23.1,"s":5,"ut":1.5},
+		{"n":"label","vs":"Machine Room"},
+		{"n":"open","vb":false},
+		{"n":"nfc-reader","vd":"aGkgCg"}
+	]`
+	message, err := senml.Decode([]byte(data), senml.JSON)
+	if err != nil {
+		t.Error("Decoding JSON failed: ", err)
+		return
+	}
+
+	records, err := message.ResolveRecords()
+	if err != nil {
+		t.Error("Resolving the records failed: ", err)
+		return
+	}
+	if len(records) != 4 {
+		t.Error("The resolved message has an unexpected number of records")
+		return
+	}
+
+	var recordsByName = map[string]senml.ResolvedRecord{}
+	for _, record := range records {
+		recordsByName[record.Name] = record
+	}
+
+	var temp = recordsByName["urn:dev:ow:10e2073a01080063:temp"]
+	if value, ok := temp.Value.AsFloat(); !ok || value != 23.1 || temp.Value.Kind() != senml.FloatValue {
+		t.Error("The float value was not resolved properly")
+	}
+	if temp.Unit != "Cel" {
+		t.Error("The unit was not resolved properly")
+	}
+	if !temp.HasSum || temp.Sum != 5 {
+		t.Error("The sum was not resolved properly")
+	}
+	if !temp.Time.Equal(time.Unix(1276020076, 1000000)) {
+		t.Error("The time was not resolved properly: ", temp.Time)
+	}
+	if temp.UpdateTime != 1500*time.Millisecond {
+		t.Error("The update time was not resolved properly")
+	}
+	if _, ok := temp.Value.AsString(); ok {
+		t.Error("The float value should not be accessible as a string")
+	}
+
+	var label = recordsByName["urn:dev:ow:10e2073a01080063:label"]
+	if value, ok := label.Value.AsString(); !ok || value != "Machine Room" {
+		t.Error("The string value was not resolved properly")
+	}
+	if label.HasSum || label.Unit != "" {
+		t.Error("The record has fields set which are not present")
+	}
+
+	var open = recordsByName["urn:dev:ow:10e2073a01080063:open"]
+	if value, ok := open.Value.AsBool(); !ok || value {
+		t.Error("The bool value was not resolved properly")
+	}
+
+	var reader = recordsByName["urn:dev:ow:10e2073a01080063:nfc-reader"]
+	if value, ok := reader.Value.AsData(); !ok || string(value) != "hi \n" {
+		t.Error("The data value was not decoded from base64url")
+	}
+	if !reader.Time.Equal(temp.Time) {
+		t.Error("The base time was not applied to a record without time")
+	}
+}
+
+func TestResolverResolveRecord(t *testing.T) {
+	var name = "test"
+	var sum float64 = 1
+	var resolver = senml.NewResolver()
+	record, err := resolver.ResolveRecord(senml.Record{Name: &name, Sum: &sum})
+	if err != nil {
+		t.Error("Resolving the record failed: ", err)
+		return
+	}
+	if record.Name != name || record.Value.Kind() != senml.NoValue || !record.HasSum {
+		t.Error("The record was not resolved properly")
+	}
+	if !record.Time.IsZero() {
+		t.Error("The time of a record without time should be zero")
+	}
+
+	_, err = resolver.ResolveRecord(senml.Record{Name: &name})
+	if err == nil {
+		t.Error("Resolving a record with no value or sum should result in an error")
+	}
+}
+
+func TestValueConstructors(t *testing.T) {
+	if value, ok := senml.NewFloatValue(1).AsFloat(); !ok || value != 1 {
+		t.Error("NewFloatValue does not hold the value")
+	}
+	if value, ok := senml.NewBoolValue(true).AsBool(); !ok || !value {
+		t.Error("NewBoolValue does not hold the value")
+	}
+	if value, ok := senml.NewStringValue("a").AsString(); !ok || value != "a" {
+		t.Error("NewStringValue does not hold the value")
+	}
+	if value, ok := senml.NewDataValue([]byte{1}).AsData(); !ok || len(value) != 1 {
+		t.Error("NewDataValue does not hold the value")
+	}
+	if _, ok := (senml.Value{}).AsFloat(); ok {
+		t.Error("The zero value should not hold a value")
+	}
+}
+
 func TestInvalidNameErrorFirstCharacterInvalid(t *testing.T) {
 	err := &senml.InvalidNameError{
 		Reason: senml.FirstCharacterInvalid,
@@ -908,10 +1409,11 @@ func TestInvalidNameErrorUnknown(t *testing.T) {
 	}
 }
 
-func TestUnsupportedVersionError(t *testing.T) {
-	err := &senml.UnsupportedVersionError{
-		SupportedVersion: 10,
-		GivenVersion:     11,
+func TestUnsupportedFeaturesError(t *testing.T) {
+	err := &senml.UnsupportedFeaturesError{
+		SupportedFeatures:   senml.SupportedFeatures,
+		GivenVersion:        11,
+		UnsupportedFeatures: 1,
 	}
 	message := err.Error()
 	if message == "" {
@@ -938,6 +1440,16 @@ func TestMissingValueError(t *testing.T) {
 	}
 }
 
+func TestInvalidDataValueError(t *testing.T) {
+	err := &senml.InvalidDataValueError{
+		DataValue: "aGkgCg==",
+	}
+	message := err.Error()
+	if !strings.Contains(message, "aGkgCg==") {
+		t.Error("The error message does not contain the data value.")
+	}
+}
+
 func TestUnsupportedFormatError(t *testing.T) {
 	err := &senml.UnsupportedFormatError{
 		GivenFormat: -1,
@@ -0,0 +1,325 @@
+package senml
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Decoder reads the records of a SenML message one at a time from an input stream.
+// This allows processing messages which are too large to be kept in memory as a whole.
+type Decoder struct {
+	format      EncodingFormat
+	jsonDecoder *json.Decoder
+	xmlDecoder  *xml.Decoder
+	started     bool
+	done        bool
+}
+
+// NewDecoder returns a new decoder that reads the message with the given decoding format from the reader.
+// The JSON and XML formats and their SenSML variants are supported.
+func NewDecoder(reader io.Reader, format EncodingFormat) *Decoder {
+	var decoder = &Decoder{
+		format: format,
+	}
+	switch format.packFormat() {
+	case JSON:
+		decoder.jsonDecoder = json.NewDecoder(reader)
+	case XML:
+		decoder.xmlDecoder = xml.NewDecoder(reader)
+	}
+	return decoder
+}
+
+// Next returns the next non-resolved record of the message. It returns io.EOF if there are no more records.
+func (decoder *Decoder) Next() (record Record, err error) {
+	if decoder.done {
+		err = io.EOF
+		return
+	}
+	switch decoder.format.packFormat() {
+	case JSON:
+		record, err = decoder.nextJSON()
+	case XML:
+		record, err = decoder.nextXML()
+	default:
+		err = newUnsupportedFormatError(decoder.format)
+	}
+	if err == io.EOF {
+		decoder.done = true
+	}
+	return
+}
+
+func (decoder *Decoder) nextJSON() (record Record, err error) {
+	if !decoder.started {
+		var token json.Token
+		token, err = decoder.jsonDecoder.Token()
+		if err != nil {
+			return
+		}
+		if delimiter, ok := token.(json.Delim); !ok || delimiter != '[' {
+			err = &json.UnmarshalTypeError{
+				Value:  fmt.Sprint(token),
+				Type:   reflect.TypeOf([]Record{}),
+				Offset: decoder.jsonDecoder.InputOffset(),
+			}
+			return
+		}
+		decoder.started = true
+	}
+
+	if !decoder.jsonDecoder.More() {
+		// consume the closing bracket of the array
+		if _, err = decoder.jsonDecoder.Token(); err != nil {
+			return
+		}
+		err = io.EOF
+		return
+	}
+	err = decoder.jsonDecoder.Decode(&record)
+	return
+}
+
+func (decoder *Decoder) nextXML() (record Record, err error) {
+	for {
+		var token xml.Token
+		token, err = decoder.xmlDecoder.Token()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			if !decoder.started {
+				if element.Name.Space != xmlNamespace || element.Name.Local != "sensml" {
+					err = xml.UnmarshalError(fmt.Sprintf("expected element <sensml> in name space %v but have <%v> in name space %q", xmlNamespace, element.Name.Local, element.Name.Space))
+					return
+				}
+				decoder.started = true
+				continue
+			}
+			if element.Name.Local != "senml" {
+				if err = decoder.xmlDecoder.Skip(); err != nil {
+					return
+				}
+				continue
+			}
+			err = decoder.xmlDecoder.DecodeElement(&record, &element)
+			return
+		case xml.EndElement:
+			err = io.EOF
+			return
+		}
+	}
+}
+
+// Encoder writes the records of a SenML message one at a time to an output stream.
+// Base fields are only written if they differ from the base fields of the preceding records, so they are usually only present on the first record.
+type Encoder struct {
+	format  EncodingFormat
+	writer  io.Writer
+	buffer  *bufio.Writer
+	started bool
+	closed  bool
+	count   int
+
+	baseName        *string
+	baseTime        *float64
+	baseUnit        *string
+	baseValue       *float64
+	baseSum         *float64
+	baseVersion     *int
+	baseContentType *string
+}
+
+// EncoderClosedError is an error which is returned when a record is written to an Encoder which was already closed.
+type EncoderClosedError struct {
+}
+
+func (err *EncoderClosedError) Error() string {
+	return "The encoder was already closed"
+}
+
+func newEncoderClosedError() *EncoderClosedError {
+	return &EncoderClosedError{}
+}
+
+// NewEncoder returns a new encoder that writes the message with the given encoding format to the writer.
+// The JSON, XML and CBOR formats and their SenSML variants are supported.
+func NewEncoder(writer io.Writer, format EncodingFormat) *Encoder {
+	return &Encoder{
+		format: format,
+		writer: writer,
+		buffer: bufio.NewWriter(writer),
+	}
+}
+
+// WriteRecord encodes the record and appends it to the message.
+func (encoder *Encoder) WriteRecord(record Record) error {
+	if encoder.closed {
+		return newEncoderClosedError()
+	}
+	if err := encoder.start(); err != nil {
+		return err
+	}
+
+	record = encoder.omitRedundantBaseFields(record)
+	switch encoder.format.packFormat() {
+	case JSON:
+		encodedRecord, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		if encoder.count > 0 {
+			encoder.buffer.WriteByte(',')
+		}
+		if encoder.format == SenSMLJSON {
+			encoder.buffer.WriteByte('\n')
+		}
+		encoder.buffer.Write(encodedRecord)
+	case XML:
+		encodedRecord, err := xml.Marshal(record)
+		if err != nil {
+			return err
+		}
+		encoder.buffer.Write(encodedRecord)
+	case CBOR:
+		var cborEncoder = cborEncoder{}
+		if err := cborEncoder.writeRecord(record); err != nil {
+			return err
+		}
+		encoder.buffer.Write(cborEncoder.buffer.Bytes())
+	}
+	encoder.count++
+	return nil
+}
+
+// Flush writes all buffered data, including the start of the message if no record was written yet, to the underlying writer.
+// If the underlying writer has a Flush method (like http.Flusher or bufio.Writer), it is called as well.
+// This allows sending a partial message, e.g. when using chunked transfer encoding.
+func (encoder *Encoder) Flush() error {
+	if !encoder.closed {
+		if err := encoder.start(); err != nil {
+			return err
+		}
+	}
+	if err := encoder.buffer.Flush(); err != nil {
+		return err
+	}
+	switch flusher := encoder.writer.(type) {
+	case interface{ Flush() error }:
+		return flusher.Flush()
+	case interface{ Flush() }:
+		flusher.Flush()
+	}
+	return nil
+}
+
+// Close writes the end of the message and flushes all buffered data. It does not close the underlying writer.
+func (encoder *Encoder) Close() error {
+	if encoder.closed {
+		return nil
+	}
+	if err := encoder.start(); err != nil {
+		return err
+	}
+	if encoder.format == SenSMLJSON {
+		encoder.buffer.WriteByte('\n')
+	}
+	switch encoder.format.packFormat() {
+	case JSON:
+		encoder.buffer.WriteByte(']')
+	case XML:
+		encoder.buffer.WriteString("</sensml>")
+	case CBOR:
+		encoder.buffer.WriteByte(cborMajorSimple<<5 | cborIndefinite)
+	}
+	encoder.closed = true
+	return encoder.Flush()
+}
+
+// start writes the start of the message if it wasn't written yet
+func (encoder *Encoder) start() error {
+	if encoder.started {
+		return nil
+	}
+	switch encoder.format.packFormat() {
+	case JSON:
+		encoder.buffer.WriteByte('[')
+	case XML:
+		encoder.buffer.WriteString(`<sensml xmlns="` + xmlNamespace + `">`)
+	case CBOR:
+		encoder.buffer.WriteByte(cborMajorArray<<5 | cborIndefinite)
+	default:
+		return newUnsupportedFormatError(encoder.format)
+	}
+	encoder.started = true
+	return nil
+}
+
+// omitRedundantBaseFields removes the base fields of the record which are equal to the base fields of the preceding records
+func (encoder *Encoder) omitRedundantBaseFields(record Record) Record {
+	if record.BaseName != nil {
+		if encoder.baseName != nil && *encoder.baseName == *record.BaseName {
+			record.BaseName = nil
+		} else {
+			var baseName = *record.BaseName
+			encoder.baseName = &baseName
+		}
+	}
+	if record.BaseTime != nil {
+		if encoder.baseTime != nil && *encoder.baseTime == *record.BaseTime {
+			record.BaseTime = nil
+		} else {
+			var baseTime = *record.BaseTime
+			encoder.baseTime = &baseTime
+		}
+	}
+	if record.BaseUnit != nil {
+		if encoder.baseUnit != nil && *encoder.baseUnit == *record.BaseUnit {
+			record.BaseUnit = nil
+		} else {
+			var baseUnit = *record.BaseUnit
+			encoder.baseUnit = &baseUnit
+		}
+	}
+	if record.BaseValue != nil {
+		if encoder.baseValue != nil && *encoder.baseValue == *record.BaseValue {
+			record.BaseValue = nil
+		} else {
+			var baseValue = *record.BaseValue
+			encoder.baseValue = &baseValue
+		}
+	}
+	if record.BaseSum != nil {
+		if encoder.baseSum != nil && *encoder.baseSum == *record.BaseSum {
+			record.BaseSum = nil
+		} else {
+			var baseSum = *record.BaseSum
+			encoder.baseSum = &baseSum
+		}
+	}
+	if record.BaseVersion != nil {
+		if encoder.baseVersion != nil && *encoder.baseVersion == *record.BaseVersion {
+			record.BaseVersion = nil
+		} else {
+			var baseVersion = *record.BaseVersion
+			encoder.baseVersion = &baseVersion
+		}
+	}
+	if record.BaseContentType != nil {
+		if encoder.baseContentType != nil && *encoder.baseContentType == *record.BaseContentType {
+			record.BaseContentType = nil
+		} else {
+			var baseContentType = *record.BaseContentType
+			encoder.baseContentType = &baseContentType
+		}
+	}
+	return record
+}
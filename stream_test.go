@@ -0,0 +1,441 @@
+package senml_test
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	senml "github.com/nkristek/go-senml"
+)
+
+func decodeAllRecords(decoder *senml.Decoder) ([]senml.Record, error) {
+	var records []senml.Record
+	for {
+		record, err := decoder.Next()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, record)
+	}
+}
+
+func TestDecoderJSON(t *testing.T) {
+	message, err := senml.Decode([]byte(jsonData), senml.JSON)
+	if err != nil {
+		t.Error("Decoding JSON failed: ", err)
+		return
+	}
+
+	records, err := decodeAllRecords(senml.NewDecoder(strings.NewReader(jsonData), senml.JSON))
+	if err != nil {
+		t.Error("Decoding the JSON stream failed: ", err)
+		return
+	}
+
+	if !reflect.DeepEqual(message.Records, records) {
+		t.Error("The records of the JSON stream differ from the decoded message")
+	}
+}
+
+func TestDecoderXML(t *testing.T) {
+	message, err := senml.Decode([]byte(xmlData), senml.XML)
+	if err != nil {
+		t.Error("Decoding XML failed: ", err)
+		return
+	}
+
+	records, err := decodeAllRecords(senml.NewDecoder(strings.NewReader(xmlData), senml.XML))
+	if err != nil {
+		t.Error("Decoding the XML stream failed: ", err)
+		return
+	}
+
+	if !reflect.DeepEqual(message.Records, records) {
+		t.Error("The records of the XML stream differ from the decoded message")
+	}
+}
+
+func TestDecoderEmpty(t *testing.T) {
+	for format, data := range map[senml.EncodingFormat]string{
+		senml.JSON: "[]",
+		senml.XML:  `<sensml xmlns="urn:ietf:params:xml:ns:senml"></sensml>`,
+	} {
+		var decoder = senml.NewDecoder(strings.NewReader(data), format)
+		for i := 0; i < 2; i++ {
+			_, err := decoder.Next()
+			if err != io.EOF {
+				t.Error("Decoding an empty stream should result in io.EOF, got: ", err)
+			}
+		}
+	}
+}
+
+func TestDecoderLargeStream(t *testing.T) {
+	const count = 10000
+	reader, writer := io.Pipe()
+	go func() {
+		io.WriteString(writer, "[")
+		for i := 0; i < count; i++ {
+			if i > 0 {
+				io.WriteString(writer, ",")
+			}
+			fmt.Fprintf(writer, `{"n":"sensor%v","v":%v}`, i, i)
+		}
+		io.WriteString(writer, "]")
+		writer.Close()
+	}()
+
+	var decoder = senml.NewDecoder(reader, senml.JSON)
+	for i := 0; i < count; i++ {
+		record, err := decoder.Next()
+		if err != nil {
+			t.Error("Decoding the JSON stream failed: ", err)
+			return
+		}
+		if record.Value == nil || *record.Value != float64(i) {
+			t.Error("The record has an unexpected value")
+			return
+		}
+	}
+	if _, err := decoder.Next(); err != io.EOF {
+		t.Error("Decoding past the last record should result in io.EOF, got: ", err)
+	}
+}
+
+func TestDecoderInvalid(t *testing.T) {
+	var invalidStreams = []struct {
+		format senml.EncodingFormat
+		data   string
+	}{
+		{senml.JSON, `{"n":"test","v":1}`},
+		{senml.JSON, `[{"n":"test","v":1},`},
+		{senml.JSON, `[{"n":"test","v":"1"}]`},
+		{senml.XML, `<senml xmlns="urn:ietf:params:xml:ns:senml" n="test" v="1"></senml>`},
+		{senml.XML, `<sensml n="test" v="1"></sensml>`},
+		{senml.XML, `<sensml xmlns="urn:ietf:params:xml:ns:senml"><senml n="test" v="1"></senml>`},
+		{senml.CBOR, `[]`},
+	}
+	for _, invalidStream := range invalidStreams {
+		_, err := decodeAllRecords(senml.NewDecoder(strings.NewReader(invalidStream.data), invalidStream.format))
+		if err == nil {
+			t.Errorf("Decoding the invalid stream %q should result in an error", invalidStream.data)
+		}
+	}
+}
+
+func TestDecoderSkipsUnknownXMLElements(t *testing.T) {
+	const data = `<sensml xmlns="urn:ietf:params:xml:ns:senml">
+		<unknown><senml n="nested" v="1"></senml></unknown>
+		<senml n="test" v="1"></senml>
+	</sensml>`
+	records, err := decodeAllRecords(senml.NewDecoder(strings.NewReader(data), senml.XML))
+	if err != nil {
+		t.Error("Decoding the XML stream failed: ", err)
+		return
+	}
+	if len(records) != 1 || records[0].Name == nil || *records[0].Name != "test" {
+		t.Error("Unknown elements were not skipped")
+	}
+}
+
+type flushRecorder struct {
+	strings.Builder
+	flushes int
+}
+
+func (recorder *flushRecorder) Flush() {
+	recorder.flushes++
+}
+
+func TestEncoderJSON(t *testing.T) {
+	message, err := senml.Decode([]byte(jsonData), senml.JSON)
+	if err != nil {
+		t.Error("Decoding JSON failed: ", err)
+		return
+	}
+
+	var builder strings.Builder
+	var encoder = senml.NewEncoder(&builder, senml.JSON)
+	for _, record := range message.Records {
+		if err := encoder.WriteRecord(record); err != nil {
+			t.Error("Writing the record failed: ", err)
+			return
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		t.Error("Closing the encoder failed: ", err)
+		return
+	}
+
+	encodedMessage, _ := message.Encode(senml.JSON)
+	if builder.String() != string(encodedMessage) {
+		t.Errorf("The JSON stream differs from the encoded message. expected: %s, got: %s", encodedMessage, builder.String())
+	}
+}
+
+func TestEncoderXML(t *testing.T) {
+	var name = "test"
+	var value float64 = 1
+	var builder strings.Builder
+	var encoder = senml.NewEncoder(&builder, senml.XML)
+	for i := 0; i < 2; i++ {
+		if err := encoder.WriteRecord(senml.Record{Name: &name, Value: &value}); err != nil {
+			t.Error("Writing the record failed: ", err)
+			return
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		t.Error("Closing the encoder failed: ", err)
+		return
+	}
+
+	message, err := senml.Decode([]byte(builder.String()), senml.XML)
+	if err != nil {
+		t.Error("Decoding the XML stream failed: ", err)
+		return
+	}
+	if len(message.Records) != 2 {
+		t.Error("The XML stream has an unexpected number of records")
+	}
+}
+
+func TestEncoderCBOR(t *testing.T) {
+	message, err := senml.Decode([]byte(jsonData), senml.JSON)
+	if err != nil {
+		t.Error("Decoding JSON failed: ", err)
+		return
+	}
+
+	var builder strings.Builder
+	var encoder = senml.NewEncoder(&builder, senml.CBOR)
+	for _, record := range message.Records {
+		if err := encoder.WriteRecord(record); err != nil {
+			t.Error("Writing the record failed: ", err)
+			return
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		t.Error("Closing the encoder failed: ", err)
+		return
+	}
+
+	decodedMessage, err := senml.Decode([]byte(builder.String()), senml.CBOR)
+	if err != nil {
+		t.Error("Decoding the CBOR stream failed: ", err)
+		return
+	}
+	if !reflect.DeepEqual(message.Records, decodedMessage.Records) {
+		t.Error("The records of the CBOR stream differ from the written records")
+	}
+}
+
+func TestEncoderEmpty(t *testing.T) {
+	for format, expected := range map[senml.EncodingFormat]string{
+		senml.JSON: "[]",
+		senml.XML:  `<sensml xmlns="urn:ietf:params:xml:ns:senml"></sensml>`,
+	} {
+		var builder strings.Builder
+		var encoder = senml.NewEncoder(&builder, format)
+		if err := encoder.Close(); err != nil {
+			t.Error("Closing the encoder failed: ", err)
+			return
+		}
+		if builder.String() != expected {
+			t.Errorf("Encoding an empty stream resulted in %q", builder.String())
+		}
+	}
+}
+
+func TestEncoderOmitsRepeatedBaseFields(t *testing.T) {
+	var baseName = "base:"
+	var otherBaseName = "other:"
+	var baseUnit = "Cel"
+	var value float64 = 1
+	var builder strings.Builder
+	var encoder = senml.NewEncoder(&builder, senml.JSON)
+	var records = []senml.Record{
+		{BaseName: &baseName, BaseUnit: &baseUnit, Value: &value},
+		{BaseName: &baseName, BaseUnit: &baseUnit, Value: &value},
+		{BaseName: &otherBaseName, BaseUnit: &baseUnit, Value: &value},
+	}
+	for _, record := range records {
+		if err := encoder.WriteRecord(record); err != nil {
+			t.Error("Writing the record failed: ", err)
+			return
+		}
+	}
+	encoder.Close()
+
+	message, err := senml.Decode([]byte(builder.String()), senml.JSON)
+	if err != nil {
+		t.Error("Decoding the JSON stream failed: ", err)
+		return
+	}
+	if message.Records[0].BaseName == nil || message.Records[0].BaseUnit == nil {
+		t.Error("The base fields of the first record were omitted")
+	}
+	if message.Records[1].BaseName != nil || message.Records[1].BaseUnit != nil {
+		t.Error("Repeated base fields were not omitted")
+	}
+	if message.Records[2].BaseName == nil || *message.Records[2].BaseName != otherBaseName || message.Records[2].BaseUnit != nil {
+		t.Error("Only changed base fields should be written")
+	}
+}
+
+func TestEncoderFlush(t *testing.T) {
+	var name = "test"
+	var value float64 = 1
+	var recorder flushRecorder
+	var encoder = senml.NewEncoder(&recorder, senml.JSON)
+	if err := encoder.WriteRecord(senml.Record{Name: &name, Value: &value}); err != nil {
+		t.Error("Writing the record failed: ", err)
+		return
+	}
+	if recorder.Len() != 0 {
+		t.Error("The record was written before flushing")
+	}
+	if err := encoder.Flush(); err != nil {
+		t.Error("Flushing the encoder failed: ", err)
+		return
+	}
+	if !strings.HasPrefix(recorder.String(), "[{") || !strings.HasSuffix(recorder.String(), `"n":"test","v":1}`) {
+		t.Errorf("Flushing resulted in an unexpected partial message: %s", recorder.String())
+	}
+	if recorder.flushes != 1 {
+		t.Error("The underlying writer was not flushed")
+	}
+}
+
+func TestEncoderClosed(t *testing.T) {
+	var name = "test"
+	var value float64 = 1
+	var builder strings.Builder
+	var encoder = senml.NewEncoder(&builder, senml.JSON)
+	encoder.Close()
+	err := encoder.WriteRecord(senml.Record{Name: &name, Value: &value})
+	if _, ok := err.(*senml.EncoderClosedError); !ok {
+		t.Error("Writing a record to a closed encoder should result in an EncoderClosedError, got: ", err)
+	}
+	if err := encoder.Close(); err != nil {
+		t.Error("Closing an encoder twice should not result in an error: ", err)
+	}
+}
+
+func TestEncoderUnsupportedFormat(t *testing.T) {
+	var name = "test"
+	var value float64 = 1
+	var builder strings.Builder
+	var encoder = senml.NewEncoder(&builder, senml.EXI)
+	err := encoder.WriteRecord(senml.Record{Name: &name, Value: &value})
+	if _, ok := err.(*senml.UnsupportedFormatError); !ok {
+		t.Error("Writing a record with an unsupported format should result in an UnsupportedFormatError, got: ", err)
+	}
+}
+
+func TestEncoderClosedError(t *testing.T) {
+	err := &senml.EncoderClosedError{}
+	message := err.Error()
+	if message == "" {
+		t.Error("The error message is empty.")
+	}
+}
+
+func TestSenSMLKeepsOrder(t *testing.T) {
+	const data = `[{"bn":"urn:dev:ow:10e2073a01080063:","bt":1.320067464e+09,"n":"temperature","t":60,"v":23.1},{"n":"temperature","t":0,"v":22.8}]`
+	message, err := senml.Decode([]byte(data), senml.SenSMLJSON)
+	if err != nil {
+		t.Error("Decoding SenSML JSON failed: ", err)
+		return
+	}
+	if !message.Stream {
+		t.Error("A message decoded from a SenSML format should be a stream")
+	}
+
+	resolvedMessage, err := message.Resolve()
+	if err != nil {
+		t.Error("Resolving the stream failed: ", err)
+		return
+	}
+	if !resolvedMessage.Stream || *resolvedMessage.Records[0].Value != 23.1 {
+		t.Error("The records of a stream should keep their order while resolving")
+	}
+
+	message, _ = senml.Decode([]byte(data), senml.JSON)
+	resolvedMessage, _ = message.Resolve()
+	if message.Stream || *resolvedMessage.Records[0].Value != 22.8 {
+		t.Error("The records of a pack should be sorted chronologically")
+	}
+}
+
+func TestSenSMLEncode(t *testing.T) {
+	message, err := senml.Decode([]byte(jsonData), senml.JSON)
+	if err != nil {
+		t.Error("Decoding JSON failed: ", err)
+		return
+	}
+
+	encodedStream, err := message.Encode(senml.SenSMLJSON)
+	if err != nil {
+		t.Error("Encoding SenSML JSON failed: ", err)
+		return
+	}
+	var lines = strings.Split(string(encodedStream), "\n")
+	if len(lines) != len(message.Records)+2 || lines[0] != "[" || lines[len(lines)-1] != "]" {
+		t.Errorf("Every record of the SenSML JSON stream should be on its own line: %s", encodedStream)
+	}
+	decodedMessage, err := senml.Decode(encodedStream, senml.SenSMLJSON)
+	if err != nil || !reflect.DeepEqual(message.Records, decodedMessage.Records) {
+		t.Error("The records changed while encoding and decoding SenSML JSON: ", err)
+	}
+
+	for format, streamFormat := range map[senml.EncodingFormat]senml.EncodingFormat{senml.XML: senml.SenSMLXML, senml.CBOR: senml.SenSMLCBOR, senml.EXI: senml.SenSMLEXI} {
+		encodedPack, _ := message.Encode(format)
+		encodedStream, err := message.Encode(streamFormat)
+		if err != nil || string(encodedPack) != string(encodedStream) {
+			t.Errorf("The SenSML representation of format %v should be the same as the SenML representation, error: %v", format, err)
+		}
+	}
+	encodedStream, _ = message.Encode(senml.SenSMLXML)
+	if !strings.HasPrefix(string(encodedStream), `<sensml xmlns="urn:ietf:params:xml:ns:senml">`) {
+		t.Errorf("The SenSML XML stream should have the sensml root element: %s", encodedStream)
+	}
+}
+
+func TestSenSMLEncoderAndDecoder(t *testing.T) {
+	message, err := senml.Decode([]byte(jsonData), senml.JSON)
+	if err != nil {
+		t.Error("Decoding JSON failed: ", err)
+		return
+	}
+
+	var builder strings.Builder
+	var encoder = senml.NewEncoder(&builder, senml.SenSMLJSON)
+	for _, record := range message.Records {
+		if err := encoder.WriteRecord(record); err != nil {
+			t.Error("Writing the record failed: ", err)
+			return
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		t.Error("Closing the encoder failed: ", err)
+		return
+	}
+	encodedMessage, _ := message.Encode(senml.SenSMLJSON)
+	if builder.String() != string(encodedMessage) {
+		t.Errorf("The SenSML JSON stream differs from the encoded message. expected: %s, got: %s", encodedMessage, builder.String())
+	}
+
+	records, err := decodeAllRecords(senml.NewDecoder(strings.NewReader(builder.String()), senml.SenSMLJSON))
+	if err != nil {
+		t.Error("Decoding the SenSML JSON stream failed: ", err)
+		return
+	}
+	if !reflect.DeepEqual(message.Records, records) {
+		t.Error("The records of the SenSML JSON stream differ from the decoded message")
+	}
+}
@@ -0,0 +1,355 @@
+package senml
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Unit describes a unit of the IANA SenML Units registry (RFC 8428 chapter 12.1) or the SenML Secondary Units registry (RFC 8798).
+type Unit struct {
+	// The symbol used in the Unit and BaseUnit fields, e.g. "Cel"
+	Symbol string
+
+	// The description of the unit, e.g. "degrees Celsius"
+	Description string
+
+	// Deprecated is true if the unit is marked in the registry as one which SHOULD NOT be produced by new implementations.
+	Deprecated bool
+
+	// The symbol of the primary unit if this is a secondary unit (RFC 8798), otherwise empty
+	PrimaryUnit string
+
+	// The factor to convert a value of a secondary unit to the primary unit: primary = Scale * secondary + Offset
+	Scale float64
+
+	// The offset to convert a value of a secondary unit to the primary unit: primary = Scale * secondary + Offset
+	Offset float64
+}
+
+// IsSecondary returns true if the unit is a secondary unit, which can be converted to its primary unit (RFC 8798).
+func (unit Unit) IsSecondary() bool {
+	return unit.PrimaryUnit != ""
+}
+
+var units = map[string]Unit{}
+
+func init() {
+	for _, unit := range []Unit{
+		// RFC 8428 chapter 12.1
+		{Symbol: "m", Description: "meter"},
+		{Symbol: "kg", Description: "kilogram"},
+		{Symbol: "g", Description: "gram", Deprecated: true},
+		{Symbol: "s", Description: "second"},
+		{Symbol: "A", Description: "ampere"},
+		{Symbol: "K", Description: "kelvin"},
+		{Symbol: "cd", Description: "candela"},
+		{Symbol: "mol", Description: "mole"},
+		{Symbol: "Hz", Description: "hertz"},
+		{Symbol: "rad", Description: "radian"},
+		{Symbol: "sr", Description: "steradian"},
+		{Symbol: "N", Description: "newton"},
+		{Symbol: "Pa", Description: "pascal"},
+		{Symbol: "J", Description: "joule"},
+		{Symbol: "W", Description: "watt"},
+		{Symbol: "C", Description: "coulomb"},
+		{Symbol: "V", Description: "volt"},
+		{Symbol: "F", Description: "farad"},
+		{Symbol: "Ohm", Description: "ohm"},
+		{Symbol: "S", Description: "siemens"},
+		{Symbol: "Wb", Description: "weber"},
+		{Symbol: "T", Description: "tesla"},
+		{Symbol: "H", Description: "henry"},
+		{Symbol: "Cel", Description: "degrees Celsius"},
+		{Symbol: "lm", Description: "lumen"},
+		{Symbol: "lx", Description: "lux"},
+		{Symbol: "Bq", Description: "becquerel"},
+		{Symbol: "Gy", Description: "gray"},
+		{Symbol: "Sv", Description: "sievert"},
+		{Symbol: "kat", Description: "katal"},
+		{Symbol: "m2", Description: "square meter (area)"},
+		{Symbol: "m3", Description: "cubic meter (volume)"},
+		{Symbol: "l", Description: "liter (volume)", Deprecated: true},
+		{Symbol: "m/s", Description: "meter per second (velocity)"},
+		{Symbol: "m/s2", Description: "meter per square second (acceleration)"},
+		{Symbol: "m3/s", Description: "cubic meter per second (flow rate)"},
+		{Symbol: "l/s", Description: "liter per second (flow rate)", Deprecated: true},
+		{Symbol: "W/m2", Description: "watt per square meter (irradiance)"},
+		{Symbol: "cd/m2", Description: "candela per square meter (luminance)"},
+		{Symbol: "bit", Description: "bit (information content)"},
+		{Symbol: "bit/s", Description: "bit per second (data rate)"},
+		{Symbol: "lat", Description: "degrees latitude"},
+		{Symbol: "lon", Description: "degrees longitude"},
+		{Symbol: "pH", Description: "pH value (acidity; logarithmic quantity)"},
+		{Symbol: "dB", Description: "decibel (logarithmic quantity)"},
+		{Symbol: "dBW", Description: "decibel relative to 1 W (power level)"},
+		{Symbol: "Bspl", Description: "bel (sound pressure level; logarithmic quantity)", Deprecated: true},
+		{Symbol: "count", Description: "1 (counter value)"},
+		{Symbol: "/", Description: "1 (ratio e.g., value of a switch)"},
+		{Symbol: "%", Description: "1 (ratio e.g., value of a switch)", Deprecated: true},
+		{Symbol: "%RH", Description: "percentage (relative humidity)"},
+		{Symbol: "%EL", Description: "percentage (remaining battery energy level)"},
+		{Symbol: "EL", Description: "seconds (remaining battery energy level)"},
+		{Symbol: "1/s", Description: "1 per second (event rate)"},
+		{Symbol: "1/min", Description: "1 per minute (event rate, \"rpm\")", Deprecated: true},
+		{Symbol: "beat/min", Description: "1 per minute (heart rate in beats per minute)", Deprecated: true},
+		{Symbol: "beats", Description: "1 (cumulative number of heart beats)", Deprecated: true},
+		{Symbol: "S/m", Description: "siemens per meter (conductivity)"},
+
+		// RFC 8798 chapter 3
+		{Symbol: "B", Description: "byte (information content)"},
+		{Symbol: "VA", Description: "volt-ampere (apparent power)"},
+		{Symbol: "VAs", Description: "volt-ampere second (apparent energy)"},
+		{Symbol: "var", Description: "volt-ampere reactive (reactive power)"},
+		{Symbol: "vars", Description: "volt-ampere-reactive second (reactive energy)"},
+		{Symbol: "J/m", Description: "joule per meter (energy per distance)"},
+		{Symbol: "kg/m3", Description: "kilogram per cubic meter (mass density, mass concentration)"},
+		{Symbol: "deg", Description: "degree (angle)", Deprecated: true},
+
+		// RFC 8798 chapter 4
+		{Symbol: "ms", Description: "millisecond", PrimaryUnit: "s", Scale: 1.0 / 1000},
+		{Symbol: "min", Description: "minute", PrimaryUnit: "s", Scale: 60},
+		{Symbol: "h", Description: "hour", PrimaryUnit: "s", Scale: 3600},
+		{Symbol: "MHz", Description: "megahertz", PrimaryUnit: "Hz", Scale: 1000000},
+		{Symbol: "kW", Description: "kilowatt", PrimaryUnit: "W", Scale: 1000},
+		{Symbol: "kVA", Description: "kilovolt-ampere", PrimaryUnit: "VA", Scale: 1000},
+		{Symbol: "kvar", Description: "kilovar", PrimaryUnit: "var", Scale: 1000},
+		{Symbol: "Ah", Description: "ampere-hour", PrimaryUnit: "C", Scale: 3600},
+		{Symbol: "Wh", Description: "watt-hour", PrimaryUnit: "J", Scale: 3600},
+		{Symbol: "kWh", Description: "kilowatt-hour", PrimaryUnit: "J", Scale: 3600000},
+		{Symbol: "varh", Description: "var-hour", PrimaryUnit: "vars", Scale: 3600},
+		{Symbol: "kvarh", Description: "kilovar-hour", PrimaryUnit: "vars", Scale: 3600000},
+		{Symbol: "kVAh", Description: "kilovolt-ampere-hour", PrimaryUnit: "VAs", Scale: 3600000},
+		{Symbol: "Wh/km", Description: "watt-hour per kilometer", PrimaryUnit: "J/m", Scale: 3.6},
+		{Symbol: "KiB", Description: "kibibyte", PrimaryUnit: "B", Scale: 1024},
+		{Symbol: "GB", Description: "gigabyte", PrimaryUnit: "B", Scale: 1e9},
+		{Symbol: "Mbit/s", Description: "megabit per second", PrimaryUnit: "bit/s", Scale: 1000000},
+		{Symbol: "B/s", Description: "byte per second", PrimaryUnit: "bit/s", Scale: 8},
+		{Symbol: "MB/s", Description: "megabyte per second", PrimaryUnit: "bit/s", Scale: 8000000},
+		{Symbol: "mV", Description: "millivolt", PrimaryUnit: "V", Scale: 1.0 / 1000},
+		{Symbol: "mA", Description: "milliampere", PrimaryUnit: "A", Scale: 1.0 / 1000},
+		{Symbol: "dBm", Description: "decibel (milliwatt)", PrimaryUnit: "dBW", Scale: 1, Offset: -30},
+		{Symbol: "ug/m3", Description: "microgram per cubic meter", PrimaryUnit: "kg/m3", Scale: 1e-9},
+		{Symbol: "mm/h", Description: "millimeter per hour", PrimaryUnit: "m/s", Scale: 1.0 / 3600000},
+		{Symbol: "m/h", Description: "meter per hour", PrimaryUnit: "m/s", Scale: 1.0 / 3600},
+		{Symbol: "ppm", Description: "parts per million", PrimaryUnit: "/", Scale: 1e-6},
+		{Symbol: "/100", Description: "percent", PrimaryUnit: "/", Scale: 1.0 / 100},
+		{Symbol: "/1000", Description: "permille", PrimaryUnit: "/", Scale: 1.0 / 1000},
+		{Symbol: "hPa", Description: "hectopascal", PrimaryUnit: "Pa", Scale: 100},
+		{Symbol: "mm", Description: "millimeter", PrimaryUnit: "m", Scale: 1.0 / 1000},
+		{Symbol: "cm", Description: "centimeter", PrimaryUnit: "m", Scale: 1.0 / 100},
+		{Symbol: "km", Description: "kilometer", PrimaryUnit: "m", Scale: 1000},
+		{Symbol: "km/h", Description: "kilometer per hour", PrimaryUnit: "m/s", Scale: 1 / 3.6},
+	} {
+		units[unit.Symbol] = unit
+	}
+}
+
+// LookupUnit returns the unit with the given symbol from the SenML units registries. It returns false if the unit is not registered.
+// Symbols are case-sensitive, e.g. "Cel" is registered but "cel" or "degC" are not.
+func LookupUnit(symbol string) (Unit, bool) {
+	unit, ok := units[symbol]
+	return unit, ok
+}
+
+// Units returns all registered units sorted by their symbol.
+func Units() []Unit {
+	var registeredUnits = make([]Unit, 0, len(units))
+	for _, unit := range units {
+		registeredUnits = append(registeredUnits, unit)
+	}
+	sort.Slice(registeredUnits, func(i, j int) bool {
+		return registeredUnits[i].Symbol < registeredUnits[j].Symbol
+	})
+	return registeredUnits
+}
+
+// UnknownUnitError is an error which is returned when the resolved unit of a record is not registered and unknown units are rejected.
+type UnknownUnitError struct {
+	// The unit which is not registered
+	Unit string
+}
+
+func (err *UnknownUnitError) Error() string {
+	return fmt.Sprintf("The unit %q is not registered", err.Unit)
+}
+
+func newUnknownUnitError(unit string) *UnknownUnitError {
+	return &UnknownUnitError{
+		Unit: unit,
+	}
+}
+
+func validateUnit(record Record) *UnknownUnitError {
+	if record.Unit != nil {
+		if _, ok := LookupUnit(*record.Unit); !ok {
+			return newUnknownUnitError(*record.Unit)
+		}
+	}
+	return nil
+}
+
+// UnitConversionError is an error which is returned when the value of a record can't be converted to the requested unit,
+// e.g. because one of the units is not registered or the units don't share the same primary unit.
+type UnitConversionError struct {
+	// The resolved unit of the record, empty if the record has no unit
+	FromUnit string
+
+	// The requested unit, empty if the record should be converted to its primary unit
+	ToUnit string
+}
+
+func (err *UnitConversionError) Error() string {
+	if err.ToUnit == "" {
+		return fmt.Sprintf("The unit %q can't be converted to a primary unit", err.FromUnit)
+	}
+	return fmt.Sprintf("The unit %q can't be converted to %q", err.FromUnit, err.ToUnit)
+}
+
+func newUnitConversionError(fromUnit string, toUnit string) *UnitConversionError {
+	return &UnitConversionError{
+		FromUnit: fromUnit,
+		ToUnit:   toUnit,
+	}
+}
+
+// ConvertToPrimaryUnit converts the Value and Sum of the record to the primary unit of its secondary unit (RFC 8798).
+// The offset of the unit is only applied to the Value, the Sum is only scaled.
+// The BaseUnit, BaseValue and BaseSum fields are applied before converting and are not set on the returned record,
+// so records which already use a primary unit are only returned with these fields applied.
+func (record Record) ConvertToPrimaryUnit() (Record, error) {
+	var converter = unitConverter{}
+	return converter.convert(record, "")
+}
+
+// ConvertUnit converts the Value and Sum of the record to the given unit, or to its primary unit if the given unit is empty.
+// The offsets of the units are only applied to the Value, the Sum is only scaled.
+// Both units have to be registered and have the same primary unit, otherwise an UnitConversionError is returned.
+// The BaseUnit, BaseValue and BaseSum fields are applied before converting and are not set on the returned record.
+func (record Record) ConvertUnit(unit string) (Record, error) {
+	var converter = unitConverter{}
+	return converter.convert(record, unit)
+}
+
+// ConvertToPrimaryUnits converts the Value and Sum of all records to the primary unit of their secondary unit (RFC 8798).
+// The base attributes of preceding records are considered like in Resolve, but the BaseUnit, BaseValue and BaseSum fields are not set on the returned records.
+func (message Message) ConvertToPrimaryUnits() (Message, error) {
+	return message.convertUnits("")
+}
+
+// ConvertUnits converts the Value and Sum of all records to the given unit.
+// The base attributes of preceding records are considered like in Resolve, but the BaseUnit, BaseValue and BaseSum fields are not set on the returned records.
+func (message Message) ConvertUnits(unit string) (Message, error) {
+	return message.convertUnits(unit)
+}
+
+func (message Message) convertUnits(unit string) (convertedMessage Message, err error) {
+	convertedMessage.XMLName = message.XMLName
+	convertedMessage.Stream = message.Stream
+	var converter = unitConverter{}
+	for _, record := range message.Records {
+		var convertedRecord Record
+		convertedRecord, err = converter.convert(record, unit)
+		if err != nil {
+			return
+		}
+		convertedMessage.Records = append(convertedMessage.Records, convertedRecord)
+	}
+	return
+}
+
+// unitConverter keeps the base attributes across records which are needed to convert the values
+type unitConverter struct {
+	baseUnit  *string
+	baseValue *float64
+	baseSum   *float64
+}
+
+// convert converts the record to the given unit, or to its primary unit if the given unit is empty
+func (converter *unitConverter) convert(record Record, unit string) (convertedRecord Record, err error) {
+	if record.BaseUnit != nil {
+		converter.baseUnit = record.BaseUnit
+	}
+	if record.BaseValue != nil {
+		converter.baseValue = record.BaseValue
+	}
+	if record.BaseSum != nil {
+		converter.baseSum = record.BaseSum
+	}
+
+	convertedRecord = record
+	convertedRecord.BaseUnit = nil
+	convertedRecord.BaseValue = nil
+	convertedRecord.BaseSum = nil
+	convertedRecord.Unit = resolveUnit(converter.baseUnit, record.Unit)
+	convertedRecord.Value = resolveValue(converter.baseValue, record.Value)
+	convertedRecord.Sum = resolveSum(converter.baseSum, record.Sum)
+
+	var fromUnit string
+	if convertedRecord.Unit != nil {
+		fromUnit = *convertedRecord.Unit
+	}
+	if unit == "" {
+		if fromUnit == "" {
+			return
+		}
+		if from, ok := LookupUnit(fromUnit); ok && !from.IsSecondary() {
+			return
+		}
+	}
+	if fromUnit == unit || (convertedRecord.Value == nil && convertedRecord.Sum == nil && fromUnit == "") {
+		return
+	}
+
+	from, ok := LookupUnit(fromUnit)
+	if !ok {
+		err = newUnitConversionError(fromUnit, unit)
+		return
+	}
+	var to = Unit{
+		Symbol: from.PrimaryUnit,
+		Scale:  1,
+	}
+	if unit != "" {
+		if to, ok = LookupUnit(unit); !ok {
+			err = newUnitConversionError(fromUnit, unit)
+			return
+		}
+	}
+	if primaryUnit(from) != primaryUnit(to) {
+		err = newUnitConversionError(fromUnit, unit)
+		return
+	}
+
+	convertedRecord.Unit = &to.Symbol
+	convertedRecord.Value = convertValue(convertedRecord.Value, from, to, true)
+	convertedRecord.Sum = convertValue(convertedRecord.Sum, from, to, false)
+	return
+}
+
+// primaryUnit returns the symbol of the primary unit of the unit
+func primaryUnit(unit Unit) string {
+	if unit.IsSecondary() {
+		return unit.PrimaryUnit
+	}
+	return unit.Symbol
+}
+
+// convertValue converts the value from one unit to another unit which has the same primary unit.
+// The offset is only applied if withOffset is set, since a sum accumulates values and is only scaled.
+func convertValue(value *float64, from Unit, to Unit, withOffset bool) *float64 {
+	if value == nil {
+		return nil
+	}
+	var fromOffset, toOffset float64
+	if withOffset {
+		fromOffset, toOffset = from.Offset, to.Offset
+	}
+	var convertedValue = *value
+	if from.IsSecondary() {
+		convertedValue = convertedValue*from.Scale + fromOffset
+	}
+	if to.IsSecondary() {
+		convertedValue = (convertedValue - toOffset) / to.Scale
+	}
+	return &convertedValue
+}
@@ -0,0 +1,306 @@
+package senml_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	senml "github.com/nkristek/go-senml"
+)
+
+func TestLookupUnit(t *testing.T) {
+	unit, ok := senml.LookupUnit("Cel")
+	if !ok {
+		t.Error("The unit Cel should be registered")
+		return
+	}
+	if unit.Description != "degrees Celsius" || unit.Deprecated || unit.IsSecondary() {
+		t.Errorf("The unit Cel has unexpected properties: %+v", unit)
+	}
+
+	unit, ok = senml.LookupUnit("beat/min")
+	if !ok || !unit.Deprecated {
+		t.Error("The unit beat/min should be registered as deprecated")
+	}
+
+	unit, ok = senml.LookupUnit("km/h")
+	if !ok || !unit.IsSecondary() || unit.PrimaryUnit != "m/s" {
+		t.Errorf("The unit km/h should be registered as secondary unit of m/s: %+v", unit)
+	}
+
+	for _, symbol := range []string{"degC", "cel", ""} {
+		if _, ok := senml.LookupUnit(symbol); ok {
+			t.Errorf("The unit %q should not be registered", symbol)
+		}
+	}
+}
+
+func TestUnitsAreSorted(t *testing.T) {
+	var units = senml.Units()
+	if len(units) == 0 {
+		t.Error("No units are registered")
+		return
+	}
+	for i := 1; i < len(units); i++ {
+		if units[i-1].Symbol >= units[i].Symbol {
+			t.Errorf("The units are not sorted: %q, %q", units[i-1].Symbol, units[i].Symbol)
+		}
+	}
+	for _, unit := range units {
+		if unit.IsSecondary() {
+			if _, ok := senml.LookupUnit(unit.PrimaryUnit); !ok {
+				t.Errorf("The primary unit %q of %q is not registered", unit.PrimaryUnit, unit.Symbol)
+			}
+		}
+	}
+}
+
+func TestResolveRejectsUnknownUnits(t *testing.T) {
+	var baseUnit = "degC"
+	var name = "test"
+	var unit = "Cel"
+	var value float64 = 1
+	message := senml.Message{
+		Records: []senml.Record{
+			{BaseUnit: &baseUnit, Name: &name, Unit: &unit, Value: &value},
+			{Name: &name, Value: &value},
+		},
+	}
+
+	if _, err := message.Resolve(); err != nil {
+		t.Error("Unknown units should not be rejected by default: ", err)
+		return
+	}
+
+	_, err := message.ResolveWithOptions(senml.ResolveOptions{RejectUnknownUnits: true})
+	unknownUnitError, ok := err.(*senml.UnknownUnitError)
+	if !ok {
+		t.Error("Resolving a record with an unknown unit should result in an UnknownUnitError, got: ", err)
+		return
+	}
+	if unknownUnitError.Unit != baseUnit {
+		t.Error("The error contains an unexpected unit")
+	}
+
+	var resolver = senml.NewResolverWithOptions(senml.ResolveOptions{RejectUnknownUnits: true})
+	if _, err := resolver.Resolve(message.Records[0]); err != nil {
+		t.Error("Resolving a record with a registered unit should not result in an error: ", err)
+	}
+	if _, err := resolver.Resolve(message.Records[1]); err == nil {
+		t.Error("Resolving a record which inherits an unknown base unit should result in an error")
+	}
+}
+
+func TestRecordConvertUnit(t *testing.T) {
+	var testCases = []struct {
+		fromUnit      string
+		toUnit        string
+		value         float64
+		expectedUnit  string
+		expectedValue float64
+		expectedSum   float64
+	}{
+		{"km/h", "", 36, "m/s", 10, 10},
+		{"dBm", "", 0, "dBW", -30, 0},
+		{"dBm", "", 10, "dBW", -20, 10},
+		{"W", "kW", 1500, "kW", 1.5, 1.5},
+		{"dBm", "dBW", 30, "dBW", 0, 30},
+		{"dBW", "dBm", 0, "dBm", 30, 0},
+		{"Wh", "kWh", 500, "kWh", 0.5, 0.5},
+		{"Cel", "", 20, "Cel", 20, 20},
+		{"Cel", "Cel", 20, "Cel", 20, 20},
+		{"/1000", "", 5, "/", 0.005, 0.005},
+		{"/1000", "/100", 5, "/100", 0.5, 0.5},
+	}
+	for _, testCase := range testCases {
+		var unit = testCase.fromUnit
+		var value = testCase.value
+		var record = senml.Record{Unit: &unit, Value: &value, Sum: &value}
+
+		var convertedRecord senml.Record
+		var err error
+		if testCase.toUnit == "" {
+			convertedRecord, err = record.ConvertToPrimaryUnit()
+		} else {
+			convertedRecord, err = record.ConvertUnit(testCase.toUnit)
+		}
+		if err != nil {
+			t.Errorf("Converting %v to %q failed: %v", testCase.fromUnit, testCase.toUnit, err)
+			continue
+		}
+		if convertedRecord.Unit == nil || *convertedRecord.Unit != testCase.expectedUnit {
+			t.Errorf("Converting %v to %q resulted in an unexpected unit", testCase.fromUnit, testCase.toUnit)
+			continue
+		}
+		if math.Abs(*convertedRecord.Value-testCase.expectedValue) > 1e-9 {
+			t.Errorf("Converting %v %v to %q resulted in %v, expected %v", testCase.value, testCase.fromUnit, testCase.toUnit, *convertedRecord.Value, testCase.expectedValue)
+		}
+		if math.Abs(*convertedRecord.Sum-testCase.expectedSum) > 1e-9 {
+			t.Errorf("Converting the sum %v %v to %q resulted in %v, expected %v", testCase.value, testCase.fromUnit, testCase.toUnit, *convertedRecord.Sum, testCase.expectedSum)
+		}
+		if *record.Unit != testCase.fromUnit || *record.Value != testCase.value {
+			t.Error("The original record was modified")
+		}
+	}
+}
+
+func TestSecondaryUnits(t *testing.T) {
+	// RFC 8798 Table 2
+	var secondaryUnits = []struct {
+		symbol      string
+		primaryUnit string
+		scale       float64
+		offset      float64
+	}{
+		{"ms", "s", 1.0 / 1000, 0},
+		{"min", "s", 60, 0},
+		{"h", "s", 3600, 0},
+		{"MHz", "Hz", 1000000, 0},
+		{"kW", "W", 1000, 0},
+		{"kVA", "VA", 1000, 0},
+		{"kvar", "var", 1000, 0},
+		{"Ah", "C", 3600, 0},
+		{"Wh", "J", 3600, 0},
+		{"kWh", "J", 3600000, 0},
+		{"varh", "vars", 3600, 0},
+		{"kvarh", "vars", 3600000, 0},
+		{"kVAh", "VAs", 3600000, 0},
+		{"Wh/km", "J/m", 3.6, 0},
+		{"KiB", "B", 1024, 0},
+		{"GB", "B", 1e9, 0},
+		{"Mbit/s", "bit/s", 1000000, 0},
+		{"B/s", "bit/s", 8, 0},
+		{"MB/s", "bit/s", 8000000, 0},
+		{"mV", "V", 1.0 / 1000, 0},
+		{"mA", "A", 1.0 / 1000, 0},
+		{"dBm", "dBW", 1, -30},
+		{"ug/m3", "kg/m3", 1e-9, 0},
+		{"mm/h", "m/s", 1.0 / 3600000, 0},
+		{"m/h", "m/s", 1.0 / 3600, 0},
+		{"ppm", "/", 1e-6, 0},
+		{"/100", "/", 1.0 / 100, 0},
+		{"/1000", "/", 1.0 / 1000, 0},
+		{"hPa", "Pa", 100, 0},
+		{"mm", "m", 1.0 / 1000, 0},
+		{"cm", "m", 1.0 / 100, 0},
+		{"km", "m", 1000, 0},
+		{"km/h", "m/s", 1 / 3.6, 0},
+	}
+	var registeredSecondaryUnits = 0
+	for _, unit := range senml.Units() {
+		if unit.IsSecondary() {
+			registeredSecondaryUnits++
+		}
+	}
+	if registeredSecondaryUnits != len(secondaryUnits) {
+		t.Errorf("%v secondary units are registered, expected %v", registeredSecondaryUnits, len(secondaryUnits))
+	}
+	for _, secondaryUnit := range secondaryUnits {
+		unit, ok := senml.LookupUnit(secondaryUnit.symbol)
+		if !ok || unit.PrimaryUnit != secondaryUnit.primaryUnit || unit.Scale != secondaryUnit.scale || unit.Offset != secondaryUnit.offset {
+			t.Errorf("The secondary unit %q is not registered as in RFC 8798: %+v", secondaryUnit.symbol, unit)
+		}
+		if _, ok := senml.LookupUnit(secondaryUnit.primaryUnit); !ok {
+			t.Errorf("The primary unit %q of %q is not registered", secondaryUnit.primaryUnit, secondaryUnit.symbol)
+		}
+	}
+}
+
+func TestRecordConvertUnitImpossible(t *testing.T) {
+	var value float64 = 1
+	var unknownUnit = "degC"
+	var unit = "m"
+	var testCases = []struct {
+		record senml.Record
+		toUnit string
+	}{
+		{senml.Record{Unit: &unknownUnit, Value: &value}, ""},
+		{senml.Record{Unit: &unknownUnit, Value: &value}, "K"},
+		{senml.Record{Unit: &unit, Value: &value}, "s"},
+		{senml.Record{Unit: &unit, Value: &value}, "degC"},
+		{senml.Record{Value: &value}, "m"},
+	}
+	for _, testCase := range testCases {
+		var err error
+		if testCase.toUnit == "" {
+			_, err = testCase.record.ConvertToPrimaryUnit()
+		} else {
+			_, err = testCase.record.ConvertUnit(testCase.toUnit)
+		}
+		conversionError, ok := err.(*senml.UnitConversionError)
+		if !ok {
+			t.Errorf("Converting to %q should result in an UnitConversionError, got: %v", testCase.toUnit, err)
+			continue
+		}
+		if conversionError.ToUnit != testCase.toUnit {
+			t.Error("The error contains an unexpected unit")
+		}
+	}
+}
+
+func TestMessageConvertUnits(t *testing.T) {
+	var baseName = "car:"
+	var baseUnit = "km/h"
+	var baseValue float64 = 30
+	var speed = "speed"
+	var distance = "distance"
+	var distanceUnit = "km"
+	var value float64 = 6
+	message := senml.Message{
+		Records: []senml.Record{
+			{BaseName: &baseName, BaseUnit: &baseUnit, BaseValue: &baseValue, Name: &speed, Value: &value},
+			{Name: &speed},
+			{Name: &distance, Unit: &distanceUnit, Value: &value},
+		},
+	}
+
+	convertedMessage, err := message.ConvertToPrimaryUnits()
+	if err != nil {
+		t.Error("Converting the message failed: ", err)
+		return
+	}
+	var expected = []struct {
+		unit  string
+		value float64
+	}{
+		{"m/s", 10},
+		{"m/s", 30 / 3.6},
+		{"m", 36000},
+	}
+	for i, record := range convertedMessage.Records {
+		if record.BaseUnit != nil || record.BaseValue != nil {
+			t.Error("The converted record should not have base attributes for the unit and value")
+		}
+		if record.BaseName == nil && i == 0 {
+			t.Error("The other base attributes should be kept")
+		}
+		if *record.Unit != expected[i].unit || math.Abs(*record.Value-expected[i].value) > 1e-9 {
+			t.Errorf("The record %v has an unexpected value: %v %v", i, *record.Value, *record.Unit)
+		}
+	}
+
+	if _, err := message.ConvertUnits("m/s"); err == nil {
+		t.Error("Converting km to m/s should result in an error")
+	}
+}
+
+func TestUnitConversionError(t *testing.T) {
+	err := &senml.UnitConversionError{
+		FromUnit: "m",
+		ToUnit:   "s",
+	}
+	message := err.Error()
+	if !strings.Contains(message, `"m"`) || !strings.Contains(message, `"s"`) {
+		t.Error("The error message does not contain the units.")
+	}
+}
+
+func TestUnknownUnitError(t *testing.T) {
+	err := &senml.UnknownUnitError{
+		Unit: "degC",
+	}
+	message := err.Error()
+	if !strings.Contains(message, "degC") {
+		t.Error("The error message does not contain the unit.")
+	}
+}
@@ -0,0 +1,227 @@
+package senml
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// MultipleValuesError is an error which is returned when more than one of the value fields Value, StringValue, BoolValue and DataValue is set on a record (RFC 8428 chapter 4.2).
+type MultipleValuesError struct {
+	// The labels of the value fields which are set on the record
+	Fields []string
+}
+
+func (err *MultipleValuesError) Error() string {
+	return fmt.Sprintf("The record has more than one value field set (%v)", strings.Join(err.Fields, ", "))
+}
+
+func newMultipleValuesError(fields []string) *MultipleValuesError {
+	return &MultipleValuesError{
+		Fields: fields,
+	}
+}
+
+// NonFiniteNumberError is an error which is returned when a numeric field of a record is NaN or infinite, which can't be represented in JSON.
+type NonFiniteNumberError struct {
+	// The non-finite number
+	Number float64
+}
+
+func (err *NonFiniteNumberError) Error() string {
+	return fmt.Sprintf("The number %v is not finite", err.Number)
+}
+
+func newNonFiniteNumberError(number float64) *NonFiniteNumberError {
+	return &NonFiniteNumberError{
+		Number: number,
+	}
+}
+
+// NegativeUpdateTimeError is an error which is returned when the UpdateTime field of a record is negative.
+type NegativeUpdateTimeError struct {
+	// The negative update time
+	UpdateTime float64
+}
+
+func (err *NegativeUpdateTimeError) Error() string {
+	return fmt.Sprintf("The update time %v is negative", err.UpdateTime)
+}
+
+func newNegativeUpdateTimeError(updateTime float64) *NegativeUpdateTimeError {
+	return &NegativeUpdateTimeError{
+		UpdateTime: updateTime,
+	}
+}
+
+// InvalidVersionError is an error which is returned when the BaseVersion field of a record is lower than 1.
+type InvalidVersionError struct {
+	// The version of the record
+	GivenVersion int
+}
+
+func (err *InvalidVersionError) Error() string {
+	return fmt.Sprintf("The version %v is invalid, it has to be at least 1", err.GivenVersion)
+}
+
+func newInvalidVersionError(givenVersion int) *InvalidVersionError {
+	return &InvalidVersionError{
+		GivenVersion: givenVersion,
+	}
+}
+
+// RecordError describes a single violation found by Validate.
+type RecordError struct {
+	// The index of the record in the message
+	Index int
+
+	// The label of the field which is invalid, e.g. "n" or "ut"
+	Field string
+
+	// The violation, e.g. an InvalidNameError or a MissingValueError
+	Err error
+}
+
+func (err *RecordError) Error() string {
+	return fmt.Sprintf("record %v, field %q: %v", err.Index, err.Field, err.Err)
+}
+
+// Unwrap returns the violation.
+func (err *RecordError) Unwrap() error {
+	return err.Err
+}
+
+// ValidationError is an error which is returned by Validate and contains every violation of the message.
+type ValidationError struct {
+	// The violations in the order of the records
+	Errors []*RecordError
+}
+
+func (err *ValidationError) Error() string {
+	var messages = make([]string, len(err.Errors))
+	for i, recordError := range err.Errors {
+		messages[i] = recordError.Error()
+	}
+	return fmt.Sprintf("The message has %v violation(s): %v", len(err.Errors), strings.Join(messages, "; "))
+}
+
+// Validate checks all records of the message against the rules of RFC 8428.
+// Unlike Resolve, it doesn't stop at the first violation. If the message is invalid, the returned error is a ValidationError which contains every violation with the index of the record and the label of the field.
+func (message Message) Validate() error {
+	var validator = recordValidator{}
+	for i, record := range message.Records {
+		validator.validate(i, record)
+	}
+	if len(validator.errors) > 0 {
+		return &ValidationError{
+			Errors: validator.errors,
+		}
+	}
+	return nil
+}
+
+// recordValidator keeps the base attributes across records, since they are needed to validate the resolved names and values
+type recordValidator struct {
+	baseName    *string
+	baseValue   *float64
+	baseSum     *float64
+	baseVersion *int
+	errors      []*RecordError
+}
+
+func (validator *recordValidator) add(index int, field string, err error) {
+	validator.errors = append(validator.errors, &RecordError{
+		Index: index,
+		Field: field,
+		Err:   err,
+	})
+}
+
+func (validator *recordValidator) validate(index int, record Record) {
+	if record.BaseVersion != nil {
+		if *record.BaseVersion < 1 {
+			validator.add(index, "bver", newInvalidVersionError(*record.BaseVersion))
+		} else if unsupportedFeaturesError := validateVersion(*record.BaseVersion); unsupportedFeaturesError != nil {
+			validator.add(index, "bver", unsupportedFeaturesError)
+		} else if validator.baseVersion == nil {
+			var baseVersion = *record.BaseVersion
+			validator.baseVersion = &baseVersion
+		} else if *record.BaseVersion != *validator.baseVersion {
+			validator.add(index, "bver", newDifferentVersionError(*validator.baseVersion, *record.BaseVersion))
+		}
+	} else if validator.baseVersion == nil {
+		var defaultVersion = SupportedVersion
+		validator.baseVersion = &defaultVersion
+	}
+	if record.BaseName != nil {
+		validator.baseName = record.BaseName
+	}
+	if record.BaseValue != nil {
+		validator.baseValue = record.BaseValue
+	}
+	if record.BaseSum != nil {
+		validator.baseSum = record.BaseSum
+	}
+
+	var baseOnly = record.HasOnlyBaseFields()
+	if !baseOnly {
+		if _, err := resolveName(validator.baseName, record.Name, StrictNameValidation); err != nil {
+			validator.add(index, "n", err)
+		}
+	}
+
+	for _, field := range []struct {
+		label  string
+		number *float64
+	}{
+		{"bt", record.BaseTime},
+		{"bv", record.BaseValue},
+		{"bs", record.BaseSum},
+		{"v", record.Value},
+		{"s", record.Sum},
+		{"t", record.Time},
+		{"ut", record.UpdateTime},
+	} {
+		if field.number != nil && (math.IsNaN(*field.number) || math.IsInf(*field.number, 0)) {
+			validator.add(index, field.label, newNonFiniteNumberError(*field.number))
+		}
+	}
+	if record.UpdateTime != nil && *record.UpdateTime < 0 {
+		validator.add(index, "ut", newNegativeUpdateTimeError(*record.UpdateTime))
+	}
+
+	var valueFields []string
+	if record.Value != nil {
+		valueFields = append(valueFields, "v")
+	}
+	if record.StringValue != nil {
+		valueFields = append(valueFields, "vs")
+	}
+	if record.BoolValue != nil {
+		valueFields = append(valueFields, "vb")
+	}
+	if record.DataValue != nil {
+		valueFields = append(valueFields, "vd")
+	}
+	if len(valueFields) > 1 {
+		validator.add(index, valueFields[1], newMultipleValuesError(valueFields))
+	}
+
+	if err := validateExtensions(record); err != nil {
+		validator.add(index, err.Label, err)
+	}
+
+	var resolvedRecord = Record{
+		Value:       resolveValue(validator.baseValue, record.Value),
+		StringValue: record.StringValue,
+		BoolValue:   record.BoolValue,
+		DataValue:   record.DataValue,
+		Sum:         resolveSum(validator.baseSum, record.Sum),
+	}
+	if err := validateRecordHasValue(resolvedRecord); err != nil && !baseOnly {
+		validator.add(index, "v", err)
+	}
+	if err := validateDataValue(resolvedRecord); err != nil {
+		validator.add(index, "vd", err)
+	}
+}
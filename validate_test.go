@@ -0,0 +1,157 @@
+package senml_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	senml "github.com/nkristek/go-senml"
+)
+
+func TestValidateValidMessage(t *testing.T) {
+	for format, data := range map[senml.EncodingFormat]string{
+		senml.JSON: jsonData,
+		senml.XML:  xmlData,
+	} {
+		message, err := senml.Decode([]byte(data), format)
+		if err != nil {
+			t.Error("Decoding the message failed: ", err)
+			return
+		}
+		if err := message.Validate(); err != nil {
+			t.Error("Validating a valid message should not result in an error: ", err)
+		}
+	}
+}
+
+func TestValidateReportsAllViolations(t *testing.T) {
+	var name = "test"
+	var invalidName = "-test"
+	var value float64 = 1
+	var nan = math.NaN()
+	var infinity = math.Inf(1)
+	var negativeUpdateTime float64 = -1
+	var stringValue = "test"
+	var boolValue = true
+	var invalidDataValue = "aGkgCg=="
+	var invalidVersion = 0
+	var version = senml.SupportedVersion
+	var otherVersion = 6
+	message := senml.Message{
+		Records: []senml.Record{
+			{BaseVersion: &invalidVersion, Name: &name, Value: &value},
+			{Name: &invalidName, Value: &value},
+			{Name: &name, Value: &nan, Time: &infinity},
+			{Name: &name, Value: &value, UpdateTime: &negativeUpdateTime},
+			{Name: &name, Value: &value, StringValue: &stringValue, BoolValue: &boolValue},
+			{Name: &name},
+			{Name: &name, DataValue: &invalidDataValue},
+			{BaseVersion: &version, Name: &name, Value: &value},
+			{BaseVersion: &otherVersion, Name: &name, Value: &value},
+		},
+	}
+
+	err := message.Validate()
+	validationError, ok := err.(*senml.ValidationError)
+	if !ok {
+		t.Error("Validating an invalid message should result in a ValidationError, got: ", err)
+		return
+	}
+
+	var expected = []struct {
+		index int
+		field string
+		check func(err error) bool
+	}{
+		{0, "bver", func(err error) bool { _, ok := err.(*senml.InvalidVersionError); return ok }},
+		{1, "n", func(err error) bool { _, ok := err.(*senml.InvalidNameError); return ok }},
+		{2, "v", func(err error) bool { _, ok := err.(*senml.NonFiniteNumberError); return ok }},
+		{2, "t", func(err error) bool { _, ok := err.(*senml.NonFiniteNumberError); return ok }},
+		{3, "ut", func(err error) bool { _, ok := err.(*senml.NegativeUpdateTimeError); return ok }},
+		{4, "vs", func(err error) bool { _, ok := err.(*senml.MultipleValuesError); return ok }},
+		{5, "v", func(err error) bool { _, ok := err.(*senml.MissingValueError); return ok }},
+		{6, "vd", func(err error) bool { _, ok := err.(*senml.InvalidDataValueError); return ok }},
+		{8, "bver", func(err error) bool { _, ok := err.(*senml.DifferentVersionError); return ok }},
+	}
+	if len(validationError.Errors) != len(expected) {
+		t.Errorf("Expected %v violations, got %v: %v", len(expected), len(validationError.Errors), validationError)
+		return
+	}
+	for i, recordError := range validationError.Errors {
+		if recordError.Index != expected[i].index || recordError.Field != expected[i].field || !expected[i].check(recordError.Err) {
+			t.Errorf("Unexpected violation at position %v: %v", i, recordError)
+		}
+	}
+}
+
+func TestValidateUsesBaseAttributes(t *testing.T) {
+	var baseName = "urn:dev:ow:10e2073a01080063:"
+	var baseValue float64 = 1
+	var stringValue = "test"
+	message := senml.Message{
+		Records: []senml.Record{
+			{BaseName: &baseName, BaseValue: &baseValue},
+			{StringValue: &stringValue},
+		},
+	}
+
+	if err := message.Validate(); err != nil {
+		t.Error("The base attributes should be used to validate the records: ", err)
+	}
+}
+
+func TestMultipleValuesError(t *testing.T) {
+	err := &senml.MultipleValuesError{
+		Fields: []string{"v", "vs"},
+	}
+	message := err.Error()
+	if !strings.Contains(message, "v, vs") {
+		t.Error("The error message does not contain the fields.")
+	}
+}
+
+func TestNonFiniteNumberError(t *testing.T) {
+	err := &senml.NonFiniteNumberError{
+		Number: math.Inf(1),
+	}
+	message := err.Error()
+	if message == "" {
+		t.Error("The error message is empty.")
+	}
+}
+
+func TestNegativeUpdateTimeError(t *testing.T) {
+	err := &senml.NegativeUpdateTimeError{
+		UpdateTime: -1,
+	}
+	message := err.Error()
+	if message == "" {
+		t.Error("The error message is empty.")
+	}
+}
+
+func TestInvalidVersionError(t *testing.T) {
+	err := &senml.InvalidVersionError{
+		GivenVersion: 0,
+	}
+	message := err.Error()
+	if message == "" {
+		t.Error("The error message is empty.")
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	err := &senml.ValidationError{
+		Errors: []*senml.RecordError{
+			{
+				Index: 3,
+				Field: "ut",
+				Err:   &senml.NegativeUpdateTimeError{UpdateTime: -1},
+			},
+		},
+	}
+	message := err.Error()
+	if !strings.Contains(message, "record 3") || !strings.Contains(message, `"ut"`) {
+		t.Error("The error message does not contain the record index and field.")
+	}
+}